@@ -3,6 +3,7 @@ package pgx
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"github.com/jackc/pgconn"
 )
@@ -18,8 +19,10 @@ type Batch struct {
 	items []*batchItem
 }
 
-// Queue queues a query to batch b. query can be an SQL query or the name of a prepared statement.
+// Queue queues a query to batch b. query can be an SQL query or the name of a prepared statement. arguments may be a
+// single NamedArgs.
 func (b *Batch) Queue(query string, arguments ...interface{}) {
+	query, arguments = rewriteNamedArgs(query, arguments)
 	b.items = append(b.items, &batchItem{
 		query:     query,
 		arguments: arguments,
@@ -31,6 +34,26 @@ func (b *Batch) Len() int {
 	return len(b.items)
 }
 
+// QueuedQuery describes a query that has been queued in a Batch.
+type QueuedQuery struct {
+	SQL       string
+	Arguments []interface{}
+}
+
+// QueuedQueries returns a description of each query that has been queued so far in the order they were queued. The
+// returned slice is a copy and modifying it does not affect b. It is safe to call QueuedQueries after b has been sent
+// with SendBatch.
+func (b *Batch) QueuedQueries() []QueuedQuery {
+	queries := make([]QueuedQuery, len(b.items))
+	for i, bi := range b.items {
+		queries[i] = QueuedQuery{
+			SQL:       bi.query,
+			Arguments: append([]interface{}(nil), bi.arguments...),
+		}
+	}
+	return queries
+}
+
 type BatchResults interface {
 	// Exec reads the results from the next query in the batch as if the query has been sent with Conn.Exec.
 	Exec() (pgconn.CommandTag, error)
@@ -47,6 +70,66 @@ type BatchResults interface {
 	Close() error
 }
 
+// ErrBatchQueryNotExecuted is reported by ExecBatch for queued queries that were not executed because an earlier query
+// in the batch failed.
+var ErrBatchQueryNotExecuted = errors.New("batch query not executed because an earlier query failed")
+
+// BatchQueryError is returned by ExecBatch when a queued query fails.
+type BatchQueryError struct {
+	// Index is the position of the failed query in the batch.
+	Index int
+	SQL   string
+	Err   error
+}
+
+func (e *BatchQueryError) Error() string {
+	return fmt.Sprintf("batch query %d failed: %v", e.Index, e.Err)
+}
+
+func (e *BatchQueryError) Unwrap() error {
+	return e.Err
+}
+
+// ExecBatch sends b with conn and reads the result of every queued query as if it was sent with Exec. conn can be a
+// *Conn, a Tx, or anything else that can send a batch. It returns the command tag and error of each query indexed by
+// queue position. The error of a query that succeeded is nil.
+//
+// When a query in a batch fails PostgreSQL aborts the transaction, implicit or explicit, and skips all following
+// queries in the batch. The queries before the failed one have still been executed, but unless they were run in an
+// explicit transaction that is later committed their effects are rolled back with the implicit transaction. The
+// failed query's error is the error returned by the server and the errors of the skipped queries are
+// ErrBatchQueryNotExecuted. Savepoints cannot isolate failures within a single batch as the skipped queries are never
+// run. Queries that must succeed or fail independently need to be sent separately.
+//
+// err is a *BatchQueryError for the failed query if any query failed, otherwise it is the error from closing the batch
+// results.
+func ExecBatch(ctx context.Context, conn interface {
+	SendBatch(ctx context.Context, b *Batch) BatchResults
+}, b *Batch) (commandTags []pgconn.CommandTag, queryErrs []error, err error) {
+	br := conn.SendBatch(ctx, b)
+
+	commandTags = make([]pgconn.CommandTag, len(b.items))
+	queryErrs = make([]error, len(b.items))
+	for i := range b.items {
+		if err != nil {
+			queryErrs[i] = ErrBatchQueryNotExecuted
+			continue
+		}
+
+		commandTags[i], queryErrs[i] = br.Exec()
+		if queryErrs[i] != nil {
+			err = &BatchQueryError{Index: i, SQL: b.items[i].query, Err: queryErrs[i]}
+		}
+	}
+
+	closeErr := br.Close()
+	if err == nil {
+		err = closeErr
+	}
+
+	return commandTags, queryErrs, err
+}
+
 type batchResults struct {
 	ctx  context.Context
 	conn *Conn
@@ -72,7 +155,7 @@ func (br *batchResults) Exec() (pgconn.CommandTag, error) {
 		if br.conn.shouldLog(LogLevelError) {
 			br.conn.log(br.ctx, LogLevelError, "BatchResult.Exec", map[string]interface{}{
 				"sql":  query,
-				"args": logQueryArgs(arguments),
+				"args": br.conn.logArgs(query, arguments),
 				"err":  err,
 			})
 		}
@@ -85,14 +168,14 @@ func (br *batchResults) Exec() (pgconn.CommandTag, error) {
 		if br.conn.shouldLog(LogLevelError) {
 			br.conn.log(br.ctx, LogLevelError, "BatchResult.Exec", map[string]interface{}{
 				"sql":  query,
-				"args": logQueryArgs(arguments),
+				"args": br.conn.logArgs(query, arguments),
 				"err":  err,
 			})
 		}
 	} else if br.conn.shouldLog(LogLevelInfo) {
 		br.conn.log(br.ctx, LogLevelInfo, "BatchResult.Exec", map[string]interface{}{
 			"sql":        query,
-			"args":       logQueryArgs(arguments),
+			"args":       br.conn.logArgs(query, arguments),
 			"commandTag": commandTag,
 		})
 	}
@@ -123,7 +206,7 @@ func (br *batchResults) Query() (Rows, error) {
 		if br.conn.shouldLog(LogLevelError) {
 			br.conn.log(br.ctx, LogLevelError, "BatchResult.Query", map[string]interface{}{
 				"sql":  query,
-				"args": logQueryArgs(arguments),
+				"args": br.conn.logArgs(query, arguments),
 				"err":  rows.err,
 			})
 		}
@@ -159,7 +242,7 @@ func (br *batchResults) Close() error {
 		if br.conn.shouldLog(LogLevelInfo) {
 			br.conn.log(br.ctx, LogLevelInfo, "BatchResult.Close", map[string]interface{}{
 				"sql":  query,
-				"args": logQueryArgs(args),
+				"args": br.conn.logArgs(query, args),
 			})
 		}
 	}
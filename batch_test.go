@@ -2,6 +2,7 @@ package pgx_test
 
 import (
 	"context"
+	"errors"
 	"os"
 	"testing"
 
@@ -12,6 +13,28 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+func TestBatchQueuedQueries(t *testing.T) {
+	t.Parallel()
+
+	batch := &pgx.Batch{}
+	batch.Queue("select 1")
+	batch.Queue("insert into ledger(description, amount) values($1, $2)", "q1", 1)
+
+	queued := batch.QueuedQueries()
+	require.Len(t, queued, 2)
+	assert.Equal(t, "select 1", queued[0].SQL)
+	assert.Empty(t, queued[0].Arguments)
+	assert.Equal(t, "insert into ledger(description, amount) values($1, $2)", queued[1].SQL)
+	assert.Equal(t, []interface{}{"q1", 1}, queued[1].Arguments)
+
+	queued[1].SQL = "select 2"
+	queued[1].Arguments[0] = "modified"
+
+	again := batch.QueuedQueries()
+	assert.Equal(t, "insert into ledger(description, amount) values($1, $2)", again[1].SQL)
+	assert.Equal(t, []interface{}{"q1", 1}, again[1].Arguments)
+}
+
 func TestConnSendBatch(t *testing.T) {
 	t.Parallel()
 
@@ -829,3 +852,64 @@ func TestSendBatchSimpleProtocol(t *testing.T) {
 	assert.EqualValues(t, 3, values[0])
 	assert.False(t, rows.Next())
 }
+
+func TestExecBatch(t *testing.T) {
+	t.Parallel()
+
+	testWithAndWithoutPreferSimpleProtocol(t, func(t *testing.T, conn *pgx.Conn) {
+		mustExec(t, conn, "create temporary table ledger(id int primary key)")
+
+		batch := &pgx.Batch{}
+		batch.Queue("insert into ledger(id) values (1)")
+		batch.Queue("insert into ledger(id) values (2)")
+
+		commandTags, queryErrs, err := pgx.ExecBatch(context.Background(), conn, batch)
+		require.NoError(t, err)
+		require.Equal(t, []error{nil, nil}, queryErrs)
+		require.Len(t, commandTags, 2)
+		require.EqualValues(t, 1, commandTags[0].RowsAffected())
+		require.EqualValues(t, 1, commandTags[1].RowsAffected())
+
+		ensureConnValid(t, conn)
+	})
+}
+
+func TestExecBatchReportsFailedQuery(t *testing.T) {
+	t.Parallel()
+
+	testWithAndWithoutPreferSimpleProtocol(t, func(t *testing.T, conn *pgx.Conn) {
+		mustExec(t, conn, "create temporary table ledger(id int primary key)")
+		mustExec(t, conn, "insert into ledger(id) values (2)")
+
+		batch := &pgx.Batch{}
+		batch.Queue("insert into ledger(id) values (1)")
+		batch.Queue("insert into ledger(id) values (2)")
+		batch.Queue("insert into ledger(id) values (3)")
+
+		commandTags, queryErrs, err := pgx.ExecBatch(context.Background(), conn, batch)
+		require.Error(t, err)
+
+		var batchErr *pgx.BatchQueryError
+		require.True(t, errors.As(err, &batchErr))
+		require.Equal(t, 1, batchErr.Index)
+		require.Equal(t, "insert into ledger(id) values (2)", batchErr.SQL)
+
+		var pgErr *pgconn.PgError
+		require.True(t, errors.As(err, &pgErr))
+		require.Equal(t, "23505", pgErr.Code)
+
+		require.Len(t, queryErrs, 3)
+		require.NoError(t, queryErrs[0])
+		require.EqualValues(t, 1, commandTags[0].RowsAffected())
+		require.True(t, errors.As(queryErrs[1], &pgErr))
+		require.Equal(t, pgx.ErrBatchQueryNotExecuted, queryErrs[2])
+
+		// The implicit transaction was rolled back.
+		var n int64
+		err = conn.QueryRow(context.Background(), "select count(*) from ledger").Scan(&n)
+		require.NoError(t, err)
+		require.EqualValues(t, 1, n)
+
+		ensureConnValid(t, conn)
+	})
+}
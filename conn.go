@@ -37,6 +37,46 @@ type ConnConfig struct {
 	// QueryExOptions.SimpleProtocol.
 	PreferSimpleProtocol bool
 
+	// QueryInterceptor is called with the SQL and arguments of every statement before it is sent to the server by
+	// Exec, Query, QueryRow, QueryFunc, Prepare, and SendBatch (once per queued query), and once per call with the COPY
+	// statements generated by CopyFrom and CopyFromReader. CopyToRows calls it once with the query to copy before it is
+	// wrapped in a COPY statement. Statements pgx issues itself such as begin and commit go through Exec so they are
+	// included. Executing a statement prepared with Prepare by name does not call it again. A query with NamedArgs is
+	// intercepted after its named placeholders have been rewritten, so the interceptor sees ordinal placeholders and
+	// positional arguments. This allows NamedArgs to be combined with an interceptor that rewrites SQL.
+	//
+	// It returns the SQL to execute, which may be sql unchanged, or an error to abort the statement. An error is
+	// returned to the caller before anything is sent so the connection remains usable. If nil, SQL is not intercepted.
+	QueryInterceptor QueryInterceptorFunc
+
+	// TextFormatOIDs lists the OIDs of types that use the text format for query results and parameters even though
+	// their registered type supports the binary format. It is an escape hatch for problems with the binary format of a
+	// specific type, such as a server bug, that does not require disabling the binary format for all types.
+	// QueryResultFormats, QueryResultFormatsByOID, and QueryResultFormatModeForceBinary take precedence for results. A
+	// parameter uses the binary format anyway if its value can only be encoded in the binary format.
+	TextFormatOIDs []uint32
+
+	// StatementTimeout, LockTimeout, and IdleInTransactionSessionTimeout set the statement_timeout, lock_timeout, and
+	// idle_in_transaction_session_timeout settings for the session. They are applied with SET in a single round trip
+	// after the connection is established. They are not sent as startup RuntimeParams because connection poolers such as
+	// PgBouncer reject unknown startup parameters by default. They persist for the life of the connection, including
+	// when it is reused by a pool. Behind a pooler in transaction mode a SET only affects the server connection it was
+	// sent on, so these settings should be configured on the server or in the pooler instead. They are truncated to
+	// whole milliseconds, which is the unit PostgreSQL uses. Zero leaves the setting unchanged. They are ignored for
+	// replication connections as a physical replication connection does not accept SET.
+	//
+	// There are no fields for application_name and search_path. application_name is already a startup parameter that
+	// poolers understand and can be set in RuntimeParams or the connection string. search_path is usually configured
+	// per role or database, and otherwise can be set in RuntimeParams or with SET in AfterConnect.
+	StatementTimeout                time.Duration
+	LockTimeout                     time.Duration
+	IdleInTransactionSessionTimeout time.Duration
+
+	// LogQueryArgs formats the arguments of a query for the "args" value of the messages sent to Logger. It can be used
+	// to redact sensitive values, such as passwords, or to log less. It is only called when a message is logged. If nil,
+	// arguments are logged with long strings and byte slices truncated.
+	LogQueryArgs LogQueryArgsFunc
+
 	createdByParseConfig bool // Used to enforce created by ParseConfig rule.
 }
 
@@ -47,6 +87,9 @@ func (cc *ConnConfig) Copy() *ConnConfig {
 	newConfig := new(ConnConfig)
 	*newConfig = *cc
 	newConfig.Config = *newConfig.Config.Copy()
+	if cc.TextFormatOIDs != nil {
+		newConfig.TextFormatOIDs = append([]uint32(nil), cc.TextFormatOIDs...)
+	}
 	return newConfig
 }
 
@@ -55,6 +98,10 @@ func (cc *ConnConfig) ConnString() string { return cc.connString }
 // BuildStatementCacheFunc is a function that can be used to create a stmtcache.Cache implementation for connection.
 type BuildStatementCacheFunc func(conn *pgconn.PgConn) stmtcache.Cache
 
+// QueryInterceptorFunc is a function that can be used to inspect, rewrite, or reject SQL before it is executed. See
+// ConnConfig.QueryInterceptor.
+type QueryInterceptorFunc func(ctx context.Context, sql string, args []interface{}) (string, error)
+
 // Conn is a PostgreSQL connection handle. It is not safe for concurrent usage. Use a connection pool to manage access
 // to multiple database connections from multiple goroutines.
 type Conn struct {
@@ -75,6 +122,11 @@ type Conn struct {
 	wbuf             []byte
 	preallocatedRows []connRows
 	eqb              extendedQueryBuilder
+
+	busyRows *connRows // rows of the query in progress if it was started by Query
+	notices  *QueryNotices
+
+	textFormatOIDs map[uint32]struct{}
 }
 
 // Identifier a PostgreSQL identifier or name. Identifiers can be composed of
@@ -91,12 +143,77 @@ func (ident Identifier) Sanitize() string {
 	return strings.Join(parts, ".")
 }
 
+// QuoteIdentifier returns name quoted as a single PostgreSQL identifier that is safe for SQL interpolation. Unlike
+// Identifier.Sanitize, which silently removes NUL bytes, it returns an error if name is empty or contains a NUL byte
+// as PostgreSQL cannot represent such an identifier.
+//
+// Query parameters should always be preferred where PostgreSQL supports them. QuoteIdentifier is only intended for
+// dynamic SQL where an identifier cannot be parameterized.
+func QuoteIdentifier(name string) (string, error) {
+	if name == "" {
+		return "", errors.New("identifier must not be empty")
+	}
+	if strings.IndexByte(name, 0) != -1 {
+		return "", errors.New("identifier must not contain a NUL byte")
+	}
+	return quoteIdentifier(name), nil
+}
+
+// QuoteLiteral returns s quoted as a PostgreSQL string literal that is safe for SQL interpolation. Single quotes are
+// doubled. If s contains a backslash an escape string literal (E'...') is returned with backslashes doubled so the
+// result has the same meaning regardless of the standard_conforming_strings setting. It returns an error if s
+// contains a NUL byte as PostgreSQL text cannot contain one.
+//
+// Query parameters should always be preferred. QuoteLiteral is only intended for the rare dynamic SQL where a value
+// cannot be parameterized, such as in the body of a DO block or in utility statements like COPY options.
+func QuoteLiteral(s string) (string, error) {
+	if strings.IndexByte(s, 0) != -1 {
+		return "", errors.New("string literal must not contain a NUL byte")
+	}
+
+	return quoteLiteral(s), nil
+}
+
+// quoteLiteral is QuoteLiteral for a string that is known to not contain a NUL byte.
+func quoteLiteral(s string) string {
+	quoted := "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	if strings.IndexByte(s, '\\') != -1 {
+		quoted = "E" + strings.ReplaceAll(quoted, `\`, `\\`)
+	}
+	return quoted
+}
+
 // ErrNoRows occurs when rows are expected but none are returned.
 var ErrNoRows = errors.New("no rows in result set")
 
 // ErrInvalidLogLevel occurs on attempt to set an invalid log level.
 var ErrInvalidLogLevel = errors.New("invalid log level")
 
+// ErrConnBusy occurs when a query is started while a previous query on the same connection is still in progress. This
+// is almost always caused by a Rows that was not closed. Use errors.Is to detect it as the error returned is a
+// *ConnBusyError.
+var ErrConnBusy = errors.New("conn busy")
+
+// ConnBusyError is returned when a query is started while a previous query on the same connection is still in
+// progress. The new query is not sent and the connection remains usable once the previous query is finished, for
+// example by closing its Rows.
+type ConnBusyError struct {
+	// PriorSQL is the SQL of the unfinished query if it was started by Query. It is empty if the connection is busy
+	// with something else such as an open BatchResults.
+	PriorSQL string
+}
+
+func (e *ConnBusyError) Error() string {
+	if e.PriorSQL == "" {
+		return "conn busy"
+	}
+	return fmt.Sprintf("conn busy: rows of prior query not closed: %s", e.PriorSQL)
+}
+
+func (e *ConnBusyError) Unwrap() error {
+	return ErrConnBusy
+}
+
 // Connect establishes a connection with a PostgreSQL server with a connection string. See
 // pgconn.Connect for details.
 func Connect(ctx context.Context, connString string) (*Conn, error) {
@@ -200,6 +317,11 @@ func connect(ctx context.Context, config *ConnConfig) (c *Conn, err error) {
 		config = &configCopy
 	}
 
+	sessionTimeoutsSQL, err := sessionTimeoutsSQL(config)
+	if err != nil {
+		return nil, err
+	}
+
 	c = &Conn{
 		config:   originalConfig,
 		connInfo: pgtype.NewConnInfo(),
@@ -216,6 +338,18 @@ func connect(ctx context.Context, config *ConnConfig) (c *Conn, err error) {
 		}
 	}
 
+	// Notices are always routed through c so they can be collected by QueryNotices. An application supplied OnNotice is
+	// still called for every notice.
+	onNotice := config.Config.OnNotice
+	config.Config.OnNotice = func(pgConn *pgconn.PgConn, n *pgconn.Notice) {
+		if c.notices != nil {
+			c.notices.Notices = append(c.notices.Notices, n)
+		}
+		if onNotice != nil {
+			onNotice(pgConn, n)
+		}
+	}
+
 	if c.shouldLog(LogLevelInfo) {
 		c.log(ctx, LogLevelInfo, "Dialing PostgreSQL server", map[string]interface{}{"host": config.Config.Host})
 	}
@@ -228,6 +362,13 @@ func connect(ctx context.Context, config *ConnConfig) (c *Conn, err error) {
 	}
 
 	c.preparedStatements = make(map[string]*pgconn.StatementDescription)
+	if len(config.TextFormatOIDs) > 0 {
+		c.textFormatOIDs = make(map[uint32]struct{}, len(config.TextFormatOIDs))
+		for _, oid := range config.TextFormatOIDs {
+			c.textFormatOIDs[oid] = struct{}{}
+		}
+		c.eqb.textFormatOIDs = c.textFormatOIDs
+	}
 	c.doneChan = make(chan struct{})
 	c.closedChan = make(chan error)
 	c.wbuf = make([]byte, 0, 1024)
@@ -242,9 +383,46 @@ func connect(ctx context.Context, config *ConnConfig) (c *Conn, err error) {
 		return c, nil
 	}
 
+	if sessionTimeoutsSQL != "" {
+		_, err = c.pgConn.Exec(ctx, sessionTimeoutsSQL).ReadAll()
+		if err != nil {
+			c.pgConn.Close(ctx)
+			if c.shouldLog(LogLevelError) {
+				c.log(ctx, LogLevelError, "failed to set session timeouts", map[string]interface{}{"err": err})
+			}
+			return nil, err
+		}
+	}
+
 	return c, nil
 }
 
+// sessionTimeoutsSQL returns the SET statements for the session timeouts in config or an empty string if there are
+// none.
+func sessionTimeoutsSQL(config *ConnConfig) (string, error) {
+	timeouts := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"statement_timeout", config.StatementTimeout},
+		{"lock_timeout", config.LockTimeout},
+		{"idle_in_transaction_session_timeout", config.IdleInTransactionSessionTimeout},
+	}
+
+	var sb strings.Builder
+	for _, t := range timeouts {
+		if t.value == 0 {
+			continue
+		}
+		if t.value < time.Millisecond {
+			return "", fmt.Errorf("%s must be at least 1ms, got %v", t.name, t.value)
+		}
+		fmt.Fprintf(&sb, "set %s = %d;", t.name, t.value.Milliseconds())
+	}
+
+	return sb.String(), nil
+}
+
 // Close closes a connection. It is safe to call Close on a already closed
 // connection.
 func (c *Conn) Close(ctx context.Context) error {
@@ -266,13 +444,6 @@ func (c *Conn) Close(ctx context.Context) error {
 // name and sql arguments. This allows a code path to Prepare and Query/Exec without
 // concern for if the statement has already been prepared.
 func (c *Conn) Prepare(ctx context.Context, name, sql string) (sd *pgconn.StatementDescription, err error) {
-	if name != "" {
-		var ok bool
-		if sd, ok = c.preparedStatements[name]; ok && sd.SQL == sql {
-			return sd, nil
-		}
-	}
-
 	if c.shouldLog(LogLevelError) {
 		defer func() {
 			if err != nil {
@@ -281,6 +452,23 @@ func (c *Conn) Prepare(ctx context.Context, name, sql string) (sd *pgconn.Statem
 		}()
 	}
 
+	err = c.checkBusy()
+	if err != nil {
+		return nil, err
+	}
+
+	sql, err = c.interceptSQL(ctx, sql, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if name != "" {
+		var ok bool
+		if sd, ok = c.preparedStatements[name]; ok && sd.SQL == sql {
+			return sd, nil
+		}
+	}
+
 	sd, err = c.pgConn.Prepare(ctx, name, sql, nil)
 	if err != nil {
 		return nil, err
@@ -293,10 +481,48 @@ func (c *Conn) Prepare(ctx context.Context, name, sql string) (sd *pgconn.Statem
 	return sd, nil
 }
 
+// Describe returns the description of sql, including the OIDs of its parameters and the field descriptions of its
+// result columns, without executing it. sql may also be the name of a statement created with Prepare. If the
+// statement cache is enabled the description is taken from or added to the cache, so a later Query or Exec of the
+// same sql does not need to prepare it again. Otherwise sql is prepared as the unnamed statement.
+func (c *Conn) Describe(ctx context.Context, sql string) (sd *pgconn.StatementDescription, err error) {
+	if sd, ok := c.preparedStatements[sql]; ok {
+		return sd, nil
+	}
+
+	if c.stmtcache != nil {
+		err = c.checkBusy()
+		if err != nil {
+			return nil, err
+		}
+
+		sql, err = c.interceptSQL(ctx, sql, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		sd, err = c.stmtcache.Get(ctx, sql)
+		if err != nil {
+			if c.shouldLog(LogLevelError) {
+				c.log(ctx, LogLevelError, "Describe failed", map[string]interface{}{"err": err, "sql": sql})
+			}
+			return nil, err
+		}
+		return sd, nil
+	}
+
+	return c.Prepare(ctx, "", sql)
+}
+
 // Deallocate released a prepared statement
 func (c *Conn) Deallocate(ctx context.Context, name string) error {
+	err := c.checkBusy()
+	if err != nil {
+		return err
+	}
+
 	delete(c.preparedStatements, name)
-	_, err := c.pgConn.Exec(ctx, "deallocate "+quoteIdentifier(name)).ReadAll()
+	_, err = c.pgConn.Exec(ctx, "deallocate "+quoteIdentifier(name)).ReadAll()
 	return err
 }
 
@@ -338,6 +564,66 @@ func (c *Conn) die(err error) {
 	c.pgConn.Close(ctx)
 }
 
+// checkBusy returns a *ConnBusyError if a previous query is still in progress. Checking before sending anything keeps
+// the connection usable instead of failing in the middle of a query.
+func (c *Conn) checkBusy() error {
+	if !c.pgConn.IsBusy() {
+		return nil
+	}
+
+	err := &ConnBusyError{}
+	if c.busyRows != nil {
+		err.PriorSQL = c.busyRows.sql
+	}
+	return err
+}
+
+// resultFormatCodeForOID returns the default result format code for oid taking ConnConfig.TextFormatOIDs into account.
+func (c *Conn) resultFormatCodeForOID(oid uint32) int16 {
+	if _, ok := c.textFormatOIDs[oid]; ok {
+		return TextFormatCode
+	}
+	return c.connInfo.ResultFormatCodeForOID(oid)
+}
+
+// interceptSQL calls the QueryInterceptor if one is configured.
+func (c *Conn) interceptSQL(ctx context.Context, sql string, args []interface{}) (string, error) {
+	if c.config.QueryInterceptor == nil {
+		return sql, nil
+	}
+	return c.config.QueryInterceptor(ctx, sql, args)
+}
+
+// interceptBatch calls the QueryInterceptor for each query in b that is not a prepared statement name. It returns a
+// new Batch with the intercepted SQL so b is not modified.
+func (c *Conn) interceptBatch(ctx context.Context, b *Batch) (*Batch, error) {
+	if c.config.QueryInterceptor == nil {
+		return b, nil
+	}
+
+	ib := &Batch{items: make([]*batchItem, len(b.items))}
+	for i, bi := range b.items {
+		sql := bi.query
+		if _, ok := c.preparedStatements[sql]; !ok {
+			var err error
+			sql, err = c.interceptSQL(ctx, sql, bi.arguments)
+			if err != nil {
+				return nil, err
+			}
+		}
+		ib.items[i] = &batchItem{query: sql, arguments: bi.arguments}
+	}
+
+	return ib, nil
+}
+
+func (c *Conn) logArgs(sql string, args []interface{}) interface{} {
+	if c.config.LogQueryArgs != nil {
+		return c.config.LogQueryArgs(sql, args)
+	}
+	return logQueryArgs(args)
+}
+
 func (c *Conn) shouldLog(lvl LogLevel) bool {
 	return c.logger != nil && c.logLevel >= lvl
 }
@@ -357,6 +643,11 @@ func quoteIdentifier(s string) string {
 	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
 }
 
+// Ping checks that the connection is alive by executing an empty query with Exec. It costs one round trip using the
+// simple protocol: the server runs the empty string through its parser, which finds no statement, and answers with
+// EmptyQueryResponse and ReadyForQuery. Nothing is planned or executed. As Ping uses Exec, the QueryInterceptor is
+// called and the query is logged like any other Exec. Whether the connection is idle or in a transaction can be
+// checked without a round trip with PgConn().TxStatus().
 func (c *Conn) Ping(ctx context.Context) error {
 	_, err := c.Exec(ctx, ";")
 	return err
@@ -393,12 +684,55 @@ func connInfoFromRows(rows Rows, err error) (map[string]uint32, error) {
 // is used and the connection must be returned to the same state before any *pgx.Conn methods are again used.
 func (c *Conn) PgConn() *pgconn.PgConn { return c.pgConn }
 
+// PID returns the process ID of the PostgreSQL backend serving this connection. It is the same for the life of the
+// connection and matches pg_stat_activity.pid and pg_backend_pid(). It is also included as "pid" in the data of every
+// message logged for this connection.
+func (c *Conn) PID() uint32 { return c.pgConn.PID() }
+
 // StatementCache returns the statement cache used for this connection.
 func (c *Conn) StatementCache() stmtcache.Cache { return c.stmtcache }
 
 // ConnInfo returns the connection info used for this connection.
 func (c *Conn) ConnInfo() *pgtype.ConnInfo { return c.connInfo }
 
+// RegisterDomainType registers the domain typeName and its array type with the connection's ConnInfo using the
+// DataType of the domain's base type. typeName may be schema qualified. The base type must already be registered. For
+// a domain over another domain register the other domain first.
+//
+// PostgreSQL reports the base type of a domain for result columns so domain results can be read without registering
+// the domain. Registration is needed to encode query arguments whose type is the domain and to read arrays of the
+// domain. The domain's constraints are checked by the server.
+func (c *Conn) RegisterDomainType(ctx context.Context, typeName string) error {
+	var oid, baseOID, arrayOID uint32
+	var arrayName string
+	err := c.QueryRow(ctx, `select t.oid, t.typbasetype, t.typarray, coalesce(a.typname::text, '')
+from pg_type t
+  left join pg_type a on a.oid = t.typarray
+where t.oid = $1::text::regtype and t.typtype = 'd'`, typeName).Scan(&oid, &baseOID, &arrayOID, &arrayName)
+	if err != nil {
+		if errors.Is(err, ErrNoRows) {
+			return fmt.Errorf("%s is not a domain type", typeName)
+		}
+		return err
+	}
+
+	baseDT, ok := c.connInfo.DataTypeForOID(baseOID)
+	if !ok {
+		return fmt.Errorf("base type of domain %s with OID %d is not registered", typeName, baseOID)
+	}
+
+	c.connInfo.RegisterDataType(pgtype.DataType{Value: pgtype.NewValue(baseDT.Value), Name: typeName, OID: oid})
+
+	if _, ok := baseDT.Value.(pgtype.ValueTranscoder); ok && arrayOID != 0 {
+		newElement := func() pgtype.ValueTranscoder {
+			return pgtype.NewValue(baseDT.Value).(pgtype.ValueTranscoder)
+		}
+		c.connInfo.RegisterDataType(pgtype.DataType{Value: pgtype.NewArrayType(arrayName, oid, newElement), Name: arrayName, OID: arrayOID})
+	}
+
+	return nil
+}
+
 // Config returns a copy of config that was used to establish this connection.
 func (c *Conn) Config() *ConnConfig { return c.config.Copy() }
 
@@ -410,14 +744,14 @@ func (c *Conn) Exec(ctx context.Context, sql string, arguments ...interface{}) (
 	commandTag, err := c.exec(ctx, sql, arguments...)
 	if err != nil {
 		if c.shouldLog(LogLevelError) {
-			c.log(ctx, LogLevelError, "Exec", map[string]interface{}{"sql": sql, "args": logQueryArgs(arguments), "err": err})
+			c.log(ctx, LogLevelError, "Exec", map[string]interface{}{"sql": sql, "args": c.logArgs(sql, arguments), "err": err})
 		}
 		return commandTag, err
 	}
 
 	if c.shouldLog(LogLevelInfo) {
 		endTime := time.Now()
-		c.log(ctx, LogLevelInfo, "Exec", map[string]interface{}{"sql": sql, "args": logQueryArgs(arguments), "time": endTime.Sub(startTime), "commandTag": commandTag})
+		c.log(ctx, LogLevelInfo, "Exec", map[string]interface{}{"sql": sql, "args": c.logArgs(sql, arguments), "time": endTime.Sub(startTime), "commandTag": commandTag})
 	}
 
 	return commandTag, err
@@ -425,6 +759,8 @@ func (c *Conn) Exec(ctx context.Context, sql string, arguments ...interface{}) (
 
 func (c *Conn) exec(ctx context.Context, sql string, arguments ...interface{}) (commandTag pgconn.CommandTag, err error) {
 	simpleProtocol := c.config.PreferSimpleProtocol
+	skipStatementCache := false
+	var notices *QueryNotices
 
 optionLoop:
 	for len(arguments) > 0 {
@@ -432,15 +768,38 @@ optionLoop:
 		case QuerySimpleProtocol:
 			simpleProtocol = bool(arg)
 			arguments = arguments[1:]
+		case QuerySkipStatementCache:
+			skipStatementCache = bool(arg)
+			arguments = arguments[1:]
+		case *QueryNotices:
+			notices = arg
+			arguments = arguments[1:]
 		default:
 			break optionLoop
 		}
 	}
 
+	sql, arguments = rewriteNamedArgs(sql, arguments)
+
+	err = c.checkBusy()
+	if err != nil {
+		return nil, err
+	}
+
+	if notices != nil {
+		c.notices = notices
+		defer func() { c.notices = nil }()
+	}
+
 	if sd, ok := c.preparedStatements[sql]; ok {
 		return c.execPrepared(ctx, sd, arguments)
 	}
 
+	sql, err = c.interceptSQL(ctx, sql, arguments)
+	if err != nil {
+		return nil, err
+	}
+
 	if simpleProtocol {
 		return c.execSimpleProtocol(ctx, sql, arguments)
 	}
@@ -449,7 +808,7 @@ optionLoop:
 		return c.execSimpleProtocol(ctx, sql, arguments)
 	}
 
-	if c.stmtcache != nil {
+	if c.stmtcache != nil && !skipStatementCache {
 		sd, err := c.stmtcache.Get(ctx, sql)
 		if err != nil {
 			return nil, err
@@ -461,7 +820,7 @@ optionLoop:
 		return c.execPrepared(ctx, sd, arguments)
 	}
 
-	sd, err := c.Prepare(ctx, "", sql)
+	sd, err := c.pgConn.Prepare(ctx, "", sql, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -504,7 +863,7 @@ func (c *Conn) execParamsAndPreparedPrefix(sd *pgconn.StatementDescription, argu
 	}
 
 	for i := range sd.Fields {
-		c.eqb.AppendResultFormat(c.ConnInfo().ResultFormatCodeForOID(sd.Fields[i].DataTypeOID))
+		c.eqb.AppendResultFormat(c.resultFormatCodeForOID(sd.Fields[i].DataTypeOID))
 	}
 
 	return nil
@@ -552,21 +911,69 @@ func (c *Conn) getRows(ctx context.Context, sql string, args []interface{}) *con
 // QuerySimpleProtocol controls whether the simple or extended protocol is used to send the query.
 type QuerySimpleProtocol bool
 
+// QuerySkipStatementCache controls whether the statement cache is bypassed for a query. When true the query is
+// prepared as the unnamed statement and the statement cache is neither read nor modified. This is useful for one-off
+// queries whose SQL is unlikely to be repeated and that would otherwise evict useful statements from the cache.
+type QuerySkipStatementCache bool
+
+// QueryNotices collects the notices, such as those from RAISE NOTICE, that the server sends while a query is executed.
+// Pass a *QueryNotices as one of the leading args of Exec, Query, QueryRow, or QueryFunc. Notices are appended to
+// Notices in the order they are received until Exec returns or the Rows of the query is closed, including notices
+// received between rows. ConnConfig.OnNotice is still called for each notice.
+type QueryNotices struct {
+	Notices []*pgconn.Notice
+}
+
 // QueryResultFormats controls the result format (text=0, binary=1) of a query by result column position.
 type QueryResultFormats []int16
 
 // QueryResultFormatsByOID controls the result format (text=0, binary=1) of a query by the result column OID.
 type QueryResultFormatsByOID map[uint32]int16
 
+// QueryResultFormatMode controls how the result format of each column of a query is chosen when QueryResultFormats
+// and QueryResultFormatsByOID are not used.
+type QueryResultFormatMode int8
+
+const (
+	// QueryResultFormatModePreferBinary uses the binary format for columns whose type is registered with a binary
+	// decoder and the text format otherwise. This is the default.
+	QueryResultFormatModePreferBinary QueryResultFormatMode = iota
+
+	// QueryResultFormatModePreferText uses the text format for all columns. Types that can only be decoded from the
+	// binary format, such as record, will fail to decode.
+	QueryResultFormatModePreferText
+
+	// QueryResultFormatModeForceBinary uses the binary format for all columns. The query fails before it is sent if any
+	// column's type is not registered with a binary decoder. It cannot be combined with the simple protocol.
+	QueryResultFormatModeForceBinary
+)
+
+func (m QueryResultFormatMode) String() string {
+	switch m {
+	case QueryResultFormatModePreferBinary:
+		return "prefer binary"
+	case QueryResultFormatModePreferText:
+		return "prefer text"
+	case QueryResultFormatModeForceBinary:
+		return "force binary"
+	default:
+		return fmt.Sprintf("unknown QueryResultFormatMode %d", m)
+	}
+}
+
 // Query executes sql with args. If there is an error the returned Rows will be returned in an error state. So it is
 // allowed to ignore the error returned from Query and handle it in Rows.
 //
-// For extra control over how the query is executed, the types QuerySimpleProtocol, QueryResultFormats, and
-// QueryResultFormatsByOID may be used as the first args to control exactly how the query is executed. This is rarely
-// needed. See the documentation for those types for details.
+// For extra control over how the query is executed, the types QuerySimpleProtocol, QuerySkipStatementCache,
+// QueryResultFormats, QueryResultFormatsByOID, QueryResultFormatMode, and *QueryNotices may be used as the first args
+// to control exactly how the query is executed. This is rarely needed. See the documentation for those types for
+// details.
 func (c *Conn) Query(ctx context.Context, sql string, args ...interface{}) (Rows, error) {
 	var resultFormats QueryResultFormats
 	var resultFormatsByOID QueryResultFormatsByOID
+	var resultFormatMode QueryResultFormatMode
+	var notices *QueryNotices
+	skipStatementCache := false
 	simpleProtocol := c.config.PreferSimpleProtocol
 
 optionLoop:
@@ -578,20 +985,55 @@ optionLoop:
 		case QueryResultFormatsByOID:
 			resultFormatsByOID = arg
 			args = args[1:]
+		case QueryResultFormatMode:
+			resultFormatMode = arg
+			args = args[1:]
 		case QuerySimpleProtocol:
 			simpleProtocol = bool(arg)
 			args = args[1:]
+		case QuerySkipStatementCache:
+			skipStatementCache = bool(arg)
+			args = args[1:]
+		case *QueryNotices:
+			notices = arg
+			args = args[1:]
 		default:
 			break optionLoop
 		}
 	}
 
+	sql, args = rewriteNamedArgs(sql, args)
+
 	rows := c.getRows(ctx, sql, args)
 
-	var err error
+	err := c.checkBusy()
+	if err != nil {
+		rows.fatal(err)
+		return rows, err
+	}
+
+	if notices != nil {
+		c.notices = notices
+		rows.notices = notices
+	}
+
 	sd, ok := c.preparedStatements[sql]
 
+	if !ok {
+		sql, err = c.interceptSQL(ctx, sql, args)
+		if err != nil {
+			rows.fatal(err)
+			return rows, err
+		}
+		rows.sql = sql
+	}
+
 	if simpleProtocol && !ok {
+		if resultFormatMode == QueryResultFormatModeForceBinary {
+			rows.fatal(errors.New("QueryResultFormatModeForceBinary cannot be used with the simple protocol"))
+			return rows, rows.err
+		}
+
 		sql, err = c.sanitizeForSimpleQuery(sql, args...)
 		if err != nil {
 			rows.fatal(err)
@@ -608,13 +1050,14 @@ optionLoop:
 			return rows, err
 		}
 
+		c.busyRows = rows
 		return rows, nil
 	}
 
 	c.eqb.Reset()
 
 	if !ok {
-		if c.stmtcache != nil {
+		if c.stmtcache != nil && !skipStatementCache {
 			sd, err = c.stmtcache.Get(ctx, sql)
 			if err != nil {
 				rows.fatal(err)
@@ -658,18 +1101,32 @@ optionLoop:
 
 	if resultFormats == nil {
 		for i := range sd.Fields {
-			c.eqb.AppendResultFormat(c.ConnInfo().ResultFormatCodeForOID(sd.Fields[i].DataTypeOID))
+			var format int16
+			switch resultFormatMode {
+			case QueryResultFormatModePreferText:
+				format = TextFormatCode
+			case QueryResultFormatModeForceBinary:
+				format = c.connInfo.ResultFormatCodeForOID(sd.Fields[i].DataTypeOID)
+				if format != BinaryFormatCode {
+					rows.fatal(fmt.Errorf("cannot force binary result format: column %d (%s) with OID %d does not support binary format", i, string(sd.Fields[i].Name), sd.Fields[i].DataTypeOID))
+					return rows, rows.err
+				}
+			default:
+				format = c.resultFormatCodeForOID(sd.Fields[i].DataTypeOID)
+			}
+			c.eqb.AppendResultFormat(format)
 		}
 
 		resultFormats = c.eqb.resultFormats
 	}
 
-	if c.stmtcache != nil && c.stmtcache.Mode() == stmtcache.ModeDescribe {
+	if !skipStatementCache && c.stmtcache != nil && c.stmtcache.Mode() == stmtcache.ModeDescribe {
 		rows.resultReader = c.pgConn.ExecParams(ctx, sql, c.eqb.paramValues, sd.ParamOIDs, c.eqb.paramFormats, resultFormats)
 	} else {
 		rows.resultReader = c.pgConn.ExecPrepared(ctx, sd.Name, c.eqb.paramValues, c.eqb.paramFormats, resultFormats)
 	}
 
+	c.busyRows = rows
 	return rows, rows.err
 }
 
@@ -694,9 +1151,18 @@ type QueryFuncRow interface {
 	RawValues() [][]byte
 }
 
+// ErrStopQueryFunc can be returned by the function passed to QueryFunc to stop iterating over the rows without an error.
+var ErrStopQueryFunc = errors.New("stop QueryFunc")
+
 // QueryFunc executes sql with args. For each row returned by the query the values will scanned into the elements of
 // scans and f will be called. If any row fails to scan or f returns an error the query will be aborted and the error
 // will be returned.
+//
+// If f returns ErrStopQueryFunc no more rows are passed to f and QueryFunc returns the command tag and a nil error. The
+// server still sends the remaining rows, which are read and discarded, so the command tag is that of the complete
+// query, not the number of rows passed to f. QueryFunc does not return that number because its signature is shared
+// with the Tx and pgxpool types and cannot change without breaking them. Count the rows in f if it is needed. Use LIMIT
+// or a Cursor to avoid the server generating rows that will not be used.
 func (c *Conn) QueryFunc(ctx context.Context, sql string, args []interface{}, scans []interface{}, f func(QueryFuncRow) error) (pgconn.CommandTag, error) {
 	rows, err := c.Query(ctx, sql, args...)
 	if err != nil {
@@ -711,6 +1177,10 @@ func (c *Conn) QueryFunc(ctx context.Context, sql string, args []interface{}, sc
 		}
 
 		err = f(rows)
+		if errors.Is(err, ErrStopQueryFunc) {
+			rows.Close()
+			break
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -727,6 +1197,16 @@ func (c *Conn) QueryFunc(ctx context.Context, sql string, args []interface{}, sc
 // explicit transaction control statements are executed. The returned BatchResults must be closed before the connection
 // is used again.
 func (c *Conn) SendBatch(ctx context.Context, b *Batch) BatchResults {
+	err := c.checkBusy()
+	if err != nil {
+		return &batchResults{ctx: ctx, conn: c, err: err}
+	}
+
+	b, err = c.interceptBatch(ctx, b)
+	if err != nil {
+		return &batchResults{ctx: ctx, conn: c, err: err}
+	}
+
 	simpleProtocol := c.config.PreferSimpleProtocol
 	var sb strings.Builder
 	if simpleProtocol {
@@ -807,7 +1287,7 @@ func (c *Conn) SendBatch(ctx context.Context, b *Batch) BatchResults {
 		}
 
 		for i := range sd.Fields {
-			c.eqb.AppendResultFormat(c.ConnInfo().ResultFormatCodeForOID(sd.Fields[i].DataTypeOID))
+			c.eqb.AppendResultFormat(c.resultFormatCodeForOID(sd.Fields[i].DataTypeOID))
 		}
 
 		if sd.Name == "" {
@@ -2,6 +2,8 @@ package pgx_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"strings"
 	"sync"
@@ -102,6 +104,92 @@ func TestConnectWithPreferSimpleProtocol(t *testing.T) {
 	ensureConnValid(t, conn)
 }
 
+func TestConnectWithSessionTimeouts(t *testing.T) {
+	t.Parallel()
+
+	config := mustParseConfig(t, os.Getenv("PGX_TEST_DATABASE"))
+	config.StatementTimeout = 5 * time.Second
+	config.LockTimeout = 1500 * time.Millisecond
+	config.IdleInTransactionSessionTimeout = time.Minute
+
+	conn := mustConnect(t, config)
+	defer closeConn(t, conn)
+
+	for _, tt := range []struct {
+		name     string
+		expected string
+	}{
+		{"statement_timeout", "5s"},
+		{"lock_timeout", "1500ms"},
+		{"idle_in_transaction_session_timeout", "1min"},
+	} {
+		var s string
+		err := conn.QueryRow(context.Background(), "show "+tt.name).Scan(&s)
+		require.NoError(t, err)
+		assert.Equal(t, tt.expected, s, tt.name)
+	}
+
+	ensureConnValid(t, conn)
+}
+
+func TestConnectWithOptionsRuntimeParam(t *testing.T) {
+	t.Parallel()
+
+	// options is passed to the server in the startup message, which parses the -c settings. A backslash escapes a
+	// space in a value. It must itself be escaped in a quoted connection string value.
+	optionsConfig, err := pgx.ParseConfig(`options='-c statement_timeout=5000 -c application_name=pgx\\ options\\ test'`)
+	require.NoError(t, err)
+	options := optionsConfig.RuntimeParams["options"]
+	require.Equal(t, `-c statement_timeout=5000 -c application_name=pgx\ options\ test`, options)
+
+	config := mustParseConfig(t, os.Getenv("PGX_TEST_DATABASE"))
+	config.RuntimeParams["options"] = options
+
+	conn := mustConnect(t, config)
+	defer closeConn(t, conn)
+
+	var statementTimeout, applicationName string
+	err = conn.QueryRow(context.Background(), "select current_setting('statement_timeout'), current_setting('application_name')").Scan(&statementTimeout, &applicationName)
+	require.NoError(t, err)
+	assert.Equal(t, "5s", statementTimeout)
+	assert.Equal(t, "pgx options test", applicationName)
+
+	ensureConnValid(t, conn)
+}
+
+func TestConnectWithInvalidSessionTimeout(t *testing.T) {
+	t.Parallel()
+
+	config := mustParseConfig(t, os.Getenv("PGX_TEST_DATABASE"))
+	config.StatementTimeout = time.Microsecond
+
+	conn, err := pgx.ConnectConfig(context.Background(), config)
+	require.EqualError(t, err, "statement_timeout must be at least 1ms, got 1µs")
+	require.Nil(t, conn)
+}
+
+func TestConnPID(t *testing.T) {
+	t.Parallel()
+
+	l := &testLogger{}
+	config := mustParseConfig(t, os.Getenv("PGX_TEST_DATABASE"))
+	config.Logger = l
+	config.LogLevel = pgx.LogLevelInfo
+
+	conn := mustConnect(t, config)
+	defer closeConn(t, conn)
+
+	var pid uint32
+	err := conn.QueryRow(context.Background(), "select pg_backend_pid()").Scan(&pid)
+	require.NoError(t, err)
+	require.NotZero(t, pid)
+	require.Equal(t, pid, conn.PID())
+	require.Equal(t, conn.PgConn().PID(), conn.PID())
+
+	require.NotEmpty(t, l.logs)
+	require.Equal(t, pid, l.logs[len(l.logs)-1].data["pid"])
+}
+
 func TestConnectConfigRequiresConnConfigFromParseConfig(t *testing.T) {
 	config := &pgx.ConnConfig{}
 	require.PanicsWithValue(t, "config must be created by ParseConfig", func() {
@@ -496,6 +584,60 @@ func TestPrepareIdempotency(t *testing.T) {
 	}
 }
 
+func TestConnDescribe(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	sql := "select $1::int4 as a, $2::text as b"
+	sd, err := conn.Describe(context.Background(), sql)
+	require.NoError(t, err)
+	assert.Equal(t, []uint32{pgtype.Int4OID, pgtype.TextOID}, sd.ParamOIDs)
+	require.Len(t, sd.Fields, 2)
+	assert.Equal(t, "a", string(sd.Fields[0].Name))
+	assert.EqualValues(t, pgtype.Int4OID, sd.Fields[0].DataTypeOID)
+	assert.Equal(t, "b", string(sd.Fields[1].Name))
+	assert.EqualValues(t, pgtype.TextOID, sd.Fields[1].DataTypeOID)
+
+	if conn.StatementCache() != nil {
+		assert.Equal(t, 1, conn.StatementCache().Len())
+
+		sd2, err := conn.Describe(context.Background(), sql)
+		require.NoError(t, err)
+		assert.True(t, sd == sd2, "expected cached statement description to be reused")
+		assert.Equal(t, 1, conn.StatementCache().Len())
+	}
+
+	_, err = conn.Prepare(context.Background(), "ps", "select $1::int8")
+	require.NoError(t, err)
+	sd, err = conn.Describe(context.Background(), "ps")
+	require.NoError(t, err)
+	assert.Equal(t, []uint32{pgtype.Int8OID}, sd.ParamOIDs)
+
+	ensureConnValid(t, conn)
+}
+
+func TestConnDescribeWithoutStatementCache(t *testing.T) {
+	t.Parallel()
+
+	config := mustParseConfig(t, os.Getenv("PGX_TEST_DATABASE"))
+	config.BuildStatementCache = nil
+	conn := mustConnect(t, config)
+	defer closeConn(t, conn)
+
+	sd, err := conn.Describe(context.Background(), "select $1::bool")
+	require.NoError(t, err)
+	assert.Equal(t, []uint32{pgtype.BoolOID}, sd.ParamOIDs)
+	require.Len(t, sd.Fields, 1)
+	assert.EqualValues(t, pgtype.BoolOID, sd.Fields[0].DataTypeOID)
+
+	_, err = conn.Describe(context.Background(), "select foo")
+	require.Error(t, err)
+
+	ensureConnValid(t, conn)
+}
+
 func TestListenNotify(t *testing.T) {
 	t.Parallel()
 
@@ -793,6 +935,172 @@ func TestLogPassesContext(t *testing.T) {
 	}
 }
 
+func TestLogQueryArgs(t *testing.T) {
+	t.Parallel()
+
+	l := &testLogger{}
+	config := mustParseConfig(t, os.Getenv("PGX_TEST_DATABASE"))
+	config.Logger = l
+	config.LogQueryArgs = func(sql string, args []interface{}) interface{} {
+		if strings.Contains(sql, "password") {
+			return "redacted"
+		}
+		return pgx.LogQueryArgTypes(sql, args)
+	}
+
+	conn := mustConnect(t, config)
+	defer closeConn(t, conn)
+
+	l.logs = l.logs[0:0] // Clear logs written when establishing connection
+
+	_, err := conn.Exec(context.Background(), "select $1::text as password", "secret")
+	require.NoError(t, err)
+
+	var n int32
+	err = conn.QueryRow(context.Background(), "select $1::int4 + $2::int4", int32(1), nil).Scan(&n)
+	require.Error(t, err)
+
+	require.Len(t, l.logs, 2)
+	assert.Equal(t, "Exec", l.logs[0].msg)
+	assert.Equal(t, "redacted", l.logs[0].data["args"])
+	assert.Equal(t, "Query", l.logs[1].msg)
+	assert.Equal(t, []string{"int32", "<nil>"}, l.logs[1].data["args"])
+}
+
+func TestSamplingLogger(t *testing.T) {
+	t.Parallel()
+
+	l := &testLogger{}
+	sl := &pgx.SamplingLogger{
+		Logger:        l,
+		SampleRate:    0.01,
+		SlowThreshold: time.Second,
+		LogErrors:     true,
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 1000; i++ {
+		sl.Log(ctx, pgx.LogLevelInfo, "Query", map[string]interface{}{"sql": "select 1", "time": time.Millisecond})
+	}
+	require.Len(t, l.logs, 10)
+
+	l.logs = nil
+	sl.Log(ctx, pgx.LogLevelInfo, "Query", map[string]interface{}{"sql": "select pg_sleep(2)", "time": 2 * time.Second})
+	sl.Log(ctx, pgx.LogLevelError, "Query", map[string]interface{}{"sql": "select 1/0", "err": errors.New("division by zero")})
+	sl.Log(ctx, pgx.LogLevelInfo, "Exec", map[string]interface{}{"sql": "select 1"})
+	require.Len(t, l.logs, 2)
+	require.Equal(t, "select pg_sleep(2)", l.logs[0].data["sql"])
+	require.Equal(t, "select 1/0", l.logs[1].data["sql"])
+
+	l.logs = nil
+	sl = &pgx.SamplingLogger{Logger: l}
+	sl.Log(ctx, pgx.LogLevelError, "Query", map[string]interface{}{"err": errors.New("division by zero")})
+	require.Empty(t, l.logs)
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	t.Parallel()
+
+	for i, tt := range []struct {
+		name     string
+		expected string
+	}{
+		{name: `foo`, expected: `"foo"`},
+		{name: `Foo Bar`, expected: `"Foo Bar"`},
+		{name: `select`, expected: `"select"`},
+		{name: `foo.bar`, expected: `"foo.bar"`},
+		{name: `you should " not do this`, expected: `"you should "" not do this"`},
+		{name: `""`, expected: `""""""`},
+		{name: `back\slash`, expected: `"back\slash"`},
+		{name: `日本語`, expected: `"日本語"`},
+	} {
+		quoted, err := pgx.QuoteIdentifier(tt.name)
+		require.NoErrorf(t, err, "%d", i)
+		assert.Equalf(t, tt.expected, quoted, "%d", i)
+	}
+
+	for i, name := range []string{"", "foo\x00bar", "\x00"} {
+		_, err := pgx.QuoteIdentifier(name)
+		assert.Errorf(t, err, "%d", i)
+	}
+}
+
+func TestQuoteLiteral(t *testing.T) {
+	t.Parallel()
+
+	for i, tt := range []struct {
+		s        string
+		expected string
+	}{
+		{s: ``, expected: `''`},
+		{s: `foo`, expected: `'foo'`},
+		{s: `don't`, expected: `'don''t'`},
+		{s: `''`, expected: `''''''`},
+		{s: `back\slash`, expected: `E'back\\slash'`},
+		{s: `\'`, expected: `E'\\'''`},
+		{s: `"quoted"`, expected: `'"quoted"'`},
+		{s: `$$dollar$$`, expected: `'$$dollar$$'`},
+		{s: "line\nbreak", expected: "'line\nbreak'"},
+		{s: `日本語`, expected: `'日本語'`},
+	} {
+		quoted, err := pgx.QuoteLiteral(tt.s)
+		require.NoErrorf(t, err, "%d", i)
+		assert.Equalf(t, tt.expected, quoted, "%d", i)
+	}
+
+	for i, s := range []string{"foo\x00bar", "\x00"} {
+		_, err := pgx.QuoteLiteral(s)
+		assert.Errorf(t, err, "%d", i)
+	}
+}
+
+func TestQuoteLiteralAndIdentifierRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	inputs := []string{
+		`foo`,
+		`don't`,
+		`''`,
+		`back\slash`,
+		`\'`,
+		`\\`,
+		`ends with \`,
+		`"double" quotes`,
+		`$$dollar$$`,
+		"line\nbreak\ttab",
+		`日本語`,
+		"é́",
+	}
+
+	for _, standardConformingStrings := range []string{"on", "off"} {
+		mustExec(t, conn, "set standard_conforming_strings = "+standardConformingStrings)
+
+		for i, s := range inputs {
+			literal, err := pgx.QuoteLiteral(s)
+			require.NoError(t, err)
+			identifier, err := pgx.QuoteIdentifier(s)
+			require.NoError(t, err)
+
+			var value, column string
+			rows, err := conn.Query(context.Background(), fmt.Sprintf("select %s as %s", literal, identifier), pgx.QuerySimpleProtocol(true))
+			require.NoError(t, err)
+			for rows.Next() {
+				column = string(rows.FieldDescriptions()[0].Name)
+				require.NoError(t, rows.Scan(&value))
+			}
+			require.NoErrorf(t, rows.Err(), "%s %d", standardConformingStrings, i)
+
+			assert.Equalf(t, s, value, "%s %d", standardConformingStrings, i)
+			assert.Equalf(t, s, column, "%s %d", standardConformingStrings, i)
+		}
+	}
+
+	ensureConnValid(t, conn)
+}
+
 func TestIdentifierSanitize(t *testing.T) {
 	t.Parallel()
 
@@ -926,6 +1234,56 @@ func TestDomainType(t *testing.T) {
 	})
 }
 
+func TestConnRegisterDomainType(t *testing.T) {
+	testWithAndWithoutPreferSimpleProtocol(t, func(t *testing.T, conn *pgx.Conn) {
+		skipCockroachDB(t, conn, "Server does support domain types (https://github.com/cockroachdb/cockroach/issues/27796)")
+
+		ctx := context.Background()
+		tx, err := conn.Begin(ctx)
+		require.NoError(t, err)
+		defer tx.Rollback(ctx)
+
+		_, err = tx.Exec(ctx, "create domain pgx_short_text as text check (length(value) < 10)")
+		require.NoError(t, err)
+
+		require.NoError(t, conn.RegisterDomainType(ctx, "pgx_short_text"))
+
+		dt, ok := conn.ConnInfo().DataTypeForName("pgx_short_text")
+		require.True(t, ok)
+		require.IsType(t, &pgtype.Text{}, dt.Value)
+		_, ok = conn.ConnInfo().DataTypeForName("_pgx_short_text")
+		require.True(t, ok)
+
+		var s string
+		err = tx.QueryRow(ctx, "select $1::pgx_short_text", "hello").Scan(&s)
+		require.NoError(t, err)
+		require.Equal(t, "hello", s)
+
+		var a []string
+		err = tx.QueryRow(ctx, "select $1::pgx_short_text[]", []string{"foo", "bar"}).Scan(&a)
+		require.NoError(t, err)
+		require.Equal(t, []string{"foo", "bar"}, a)
+
+		// The domain's constraint is checked by the server.
+		_, err = tx.Exec(ctx, "select $1::pgx_short_text", "far too long")
+		var pgErr *pgconn.PgError
+		require.True(t, errors.As(err, &pgErr))
+		require.Equal(t, "23514", pgErr.Code)
+	})
+}
+
+func TestConnRegisterDomainTypeNotADomain(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	err := conn.RegisterDomainType(context.Background(), "int4")
+	require.EqualError(t, err, "int4 is not a domain type")
+
+	ensureConnValid(t, conn)
+}
+
 func TestStmtCacheInvalidationConn(t *testing.T) {
 	ctx := context.Background()
 
@@ -1074,3 +1432,94 @@ func TestInsertDurationInterval(t *testing.T) {
 		require.EqualValues(t, 1, n)
 	})
 }
+
+func TestConnQueryInterceptor(t *testing.T) {
+	t.Parallel()
+
+	var intercepted []string
+	errRejected := errors.New("select * is not allowed")
+
+	config := mustParseConfig(t, os.Getenv("PGX_TEST_DATABASE"))
+	config.QueryInterceptor = func(ctx context.Context, sql string, args []interface{}) (string, error) {
+		intercepted = append(intercepted, sql)
+		if strings.Contains(sql, "select *") {
+			return "", errRejected
+		}
+		return strings.Replace(sql, "__rewrite_me__", "42", 1), nil
+	}
+
+	conn := mustConnect(t, config)
+	defer closeConn(t, conn)
+
+	var n int32
+	err := conn.QueryRow(context.Background(), "select __rewrite_me__::int4 + $1", int32(1)).Scan(&n)
+	require.NoError(t, err)
+	assert.EqualValues(t, 43, n)
+
+	_, err = conn.Query(context.Background(), "select * from pg_class")
+	assert.Equal(t, errRejected, err)
+
+	_, err = conn.Exec(context.Background(), "select * from pg_class")
+	assert.Equal(t, errRejected, err)
+
+	_, err = conn.Exec(context.Background(), "create temporary table foo(a int4)")
+	require.NoError(t, err)
+
+	intercepted = nil
+	batch := &pgx.Batch{}
+	batch.Queue("insert into foo(a) values (__rewrite_me__)")
+	batch.Queue("select a from foo")
+	br := conn.SendBatch(context.Background(), batch)
+	_, err = br.Exec()
+	require.NoError(t, err)
+	err = br.QueryRow().Scan(&n)
+	require.NoError(t, err)
+	assert.EqualValues(t, 42, n)
+	require.NoError(t, br.Close())
+	assert.Equal(t, []string{"insert into foo(a) values (__rewrite_me__)", "select a from foo"}, intercepted)
+
+	// The original batch is not modified.
+	assert.Equal(t, "insert into foo(a) values (__rewrite_me__)", batch.QueuedQueries()[0].SQL)
+
+	batch = &pgx.Batch{}
+	batch.Queue("select 1")
+	batch.Queue("select * from foo")
+	br = conn.SendBatch(context.Background(), batch)
+	_, err = br.Exec()
+	assert.Equal(t, errRejected, err)
+	assert.Equal(t, errRejected, br.Close())
+
+	intercepted = nil
+	_, err = conn.CopyFrom(context.Background(), pgx.Identifier{"foo"}, []string{"a"}, pgx.CopyFromRows([][]interface{}{{int32(1)}}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{`copy "foo" ( "a" ) from stdin binary;`}, intercepted)
+
+	// CopyToRows intercepts the query once and uses the result to describe and copy it.
+	intercepted = nil
+	rows, err := conn.CopyToRows(context.Background(), "select __rewrite_me__::int4")
+	require.NoError(t, err)
+	require.True(t, rows.Next())
+	require.NoError(t, rows.Scan(&n))
+	assert.EqualValues(t, 42, n)
+	rows.Close()
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []string{"select __rewrite_me__::int4"}, intercepted)
+
+	_, err = conn.CopyToRows(context.Background(), "select * from foo")
+	assert.Equal(t, errRejected, err)
+
+	// Statements prepared by name are intercepted when prepared.
+	intercepted = nil
+	_, err = conn.Prepare(context.Background(), "ps", "select __rewrite_me__::int4")
+	require.NoError(t, err)
+	err = conn.QueryRow(context.Background(), "ps").Scan(&n)
+	require.NoError(t, err)
+	assert.EqualValues(t, 42, n)
+	assert.Equal(t, []string{"select __rewrite_me__::int4"}, intercepted)
+
+	// Prepare remains idempotent when the SQL is rewritten.
+	_, err = conn.Prepare(context.Background(), "ps", "select __rewrite_me__::int4")
+	require.NoError(t, err)
+
+	ensureConnValid(t, conn)
+}
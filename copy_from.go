@@ -3,12 +3,15 @@ package pgx
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgconn"
 	"github.com/jackc/pgio"
+	"github.com/jackc/pgtype"
 )
 
 // CopyFromRows returns a CopyFromSource interface over the provided rows slice
@@ -65,6 +68,130 @@ func (cts *copyFromSlice) Err() error {
 	return cts.err
 }
 
+// CopyFromChan returns a CopyFromSource interface over the rows received from ch making it usable by *Conn.CopyFrom.
+// Rows are read from ch until it is closed, so the caller is responsible for closing ch to signal the end of the data.
+// ch only carries rows; a producer that needs to abort the copy should cancel ctx. If ctx is canceled the source stops
+// receiving from ch and Err returns the context error.
+func CopyFromChan(ctx context.Context, ch <-chan []interface{}) CopyFromSource {
+	return &copyFromChan{ctx: ctx, ch: ch}
+}
+
+type copyFromChan struct {
+	ctx    context.Context
+	ch     <-chan []interface{}
+	values []interface{}
+	err    error
+}
+
+func (ctc *copyFromChan) Next() bool {
+	if ctc.err != nil {
+		return false
+	}
+
+	select {
+	case <-ctc.ctx.Done():
+		ctc.err = ctc.ctx.Err()
+		return false
+	case values, ok := <-ctc.ch:
+		if !ok {
+			return false
+		}
+		ctc.values = values
+		return true
+	}
+}
+
+func (ctc *copyFromChan) Values() ([]interface{}, error) {
+	return ctc.values, nil
+}
+
+func (ctc *copyFromChan) Err() error {
+	return ctc.err
+}
+
+// CopyFromRawRows returns a CopyFromSource interface over rows, usually the result of a Query on another connection,
+// making it usable by *Conn.CopyFrom. Values in the binary format whose OID is the same as the OID of the destination
+// column are copied without being decoded. Other values are decoded with rows.Values and encoded for the destination
+// column as usual. Columns are matched by position. rows is not closed; the caller must close it and check its error
+// after CopyFrom returns.
+func CopyFromRawRows(rows Rows) CopyFromSource {
+	return &copyFromRawRows{rows: rows}
+}
+
+type copyFromRawRows struct {
+	rows Rows
+
+	// values are the decoded values of the current row. They are only decoded when needed.
+	values    []interface{}
+	valuesErr error
+	decoded   bool
+}
+
+func (cfr *copyFromRawRows) Next() bool {
+	cfr.values = nil
+	cfr.valuesErr = nil
+	cfr.decoded = false
+	return cfr.rows.Next()
+}
+
+func (cfr *copyFromRawRows) Values() ([]interface{}, error) {
+	fieldDescriptions := cfr.rows.FieldDescriptions()
+	rawValues := cfr.rows.RawValues()
+	values := make([]interface{}, len(rawValues))
+	for i := range rawValues {
+		values[i] = &rawCopyValue{
+			src:    cfr,
+			idx:    i,
+			oid:    fieldDescriptions[i].DataTypeOID,
+			format: fieldDescriptions[i].Format,
+			buf:    rawValues[i],
+		}
+	}
+	return values, nil
+}
+
+func (cfr *copyFromRawRows) Err() error {
+	return cfr.rows.Err()
+}
+
+func (cfr *copyFromRawRows) decodedValue(idx int) (interface{}, error) {
+	if !cfr.decoded {
+		cfr.values, cfr.valuesErr = cfr.rows.Values()
+		cfr.decoded = true
+	}
+	if cfr.valuesErr != nil {
+		return nil, cfr.valuesErr
+	}
+	return cfr.values[idx], nil
+}
+
+// rawCopyValue is a value read from a source Rows by copyFromRawRows. It is handled by encodePreparedStatementArgument
+// as only it knows the OID of the destination column.
+type rawCopyValue struct {
+	src    *copyFromRawRows
+	idx    int
+	oid    uint32
+	format int16
+	buf    []byte
+}
+
+func (v *rawCopyValue) encodeCopy(ci *pgtype.ConnInfo, buf []byte, oid uint32) ([]byte, error) {
+	if v.buf == nil {
+		return pgio.AppendInt32(buf, -1), nil
+	}
+
+	if v.format == BinaryFormatCode && v.oid == oid {
+		buf = pgio.AppendInt32(buf, int32(len(v.buf)))
+		return append(buf, v.buf...), nil
+	}
+
+	value, err := v.src.decodedValue(v.idx)
+	if err != nil {
+		return nil, err
+	}
+	return encodePreparedStatementArgument(ci, buf, oid, value)
+}
+
 // CopyFromSource is the interface used by *Conn.CopyFrom as the source for copy data.
 type CopyFromSource interface {
 	// Next returns true if there is another row and makes the next row data
@@ -86,9 +213,19 @@ type copyFrom struct {
 	columnNames   []string
 	rowSrc        CopyFromSource
 	readerErrChan chan error
+
+	// bufferedRows is the number of rows encoded into the buffer that has not yet been written. rowsSent is the number
+	// of rows written to the server. Both are only accessed by the goroutine encoding rows until it finishes.
+	bufferedRows int64
+	rowsSent     int64
 }
 
 func (ct *copyFrom) run(ctx context.Context) (int64, error) {
+	err := ct.conn.checkBusy()
+	if err != nil {
+		return 0, err
+	}
+
 	quotedTableName := ct.tableName.Sanitize()
 	cbuf := &bytes.Buffer{}
 	for i, cn := range ct.columnNames {
@@ -99,7 +236,13 @@ func (ct *copyFrom) run(ctx context.Context) (int64, error) {
 	}
 	quotedColumnNames := cbuf.String()
 
-	sd, err := ct.conn.Prepare(ctx, "", fmt.Sprintf("select %s from %s", quotedColumnNames, quotedTableName))
+	copySQL, err := ct.conn.interceptSQL(ctx, fmt.Sprintf("copy %s ( %s ) from stdin binary;", quotedTableName, quotedColumnNames), nil)
+	if err != nil {
+		return 0, err
+	}
+
+	// Only the COPY statement is intercepted.
+	sd, err := ct.conn.pgConn.Prepare(ctx, "", fmt.Sprintf("select %s from %s", quotedColumnNames, quotedTableName), nil)
 	if err != nil {
 		return 0, err
 	}
@@ -137,6 +280,8 @@ func (ct *copyFrom) run(ctx context.Context) (int64, error) {
 					w.Close()
 					return
 				}
+				ct.rowsSent += ct.bufferedRows
+				ct.bufferedRows = 0
 			}
 
 			buf = buf[:0]
@@ -147,7 +292,7 @@ func (ct *copyFrom) run(ctx context.Context) (int64, error) {
 
 	startTime := time.Now()
 
-	commandTag, err := ct.conn.pgConn.CopyFrom(ctx, r, fmt.Sprintf("copy %s ( %s ) from stdin binary;", quotedTableName, quotedColumnNames))
+	commandTag, err := ct.conn.pgConn.CopyFrom(ctx, r, copySQL)
 
 	r.Close()
 	<-doneChan
@@ -159,7 +304,8 @@ func (ct *copyFrom) run(ctx context.Context) (int64, error) {
 			ct.conn.log(ctx, LogLevelInfo, "CopyFrom", map[string]interface{}{"tableName": ct.tableName, "columnNames": ct.columnNames, "time": endTime.Sub(startTime), "rowCount": rowsAffected})
 		}
 	} else if ct.conn.shouldLog(LogLevelError) {
-		ct.conn.log(ctx, LogLevelError, "CopyFrom", map[string]interface{}{"err": err, "tableName": ct.tableName, "columnNames": ct.columnNames})
+		endTime := time.Now()
+		ct.conn.log(ctx, LogLevelError, "CopyFrom", map[string]interface{}{"err": err, "tableName": ct.tableName, "columnNames": ct.columnNames, "time": endTime.Sub(startTime), "rowsSent": ct.rowsSent})
 	}
 
 	return rowsAffected, err
@@ -183,6 +329,7 @@ func (ct *copyFrom) buildCopyBuf(buf []byte, sd *pgconn.StatementDescription) (b
 				return false, nil, err
 			}
 		}
+		ct.bufferedRows++
 
 		if len(buf) > 65536 {
 			return true, buf, nil
@@ -209,3 +356,248 @@ func (c *Conn) CopyFrom(ctx context.Context, tableName Identifier, columnNames [
 
 	return ct.run(ctx)
 }
+
+// CopyFromEncodeError is returned by CopyFromDryRun when a value cannot be encoded for its column.
+type CopyFromEncodeError struct {
+	// Row is the zero based index of the row in the CopyFromSource.
+	Row int64
+
+	// Column is the name of the column of the value that could not be encoded. It is empty if the row has the wrong
+	// number of values.
+	Column string
+
+	Err error
+}
+
+func (e *CopyFromEncodeError) Error() string {
+	if e.Column == "" {
+		return fmt.Sprintf("row %d: %v", e.Row, e.Err)
+	}
+	return fmt.Sprintf("row %d column %s: %v", e.Row, e.Column, e.Err)
+}
+
+func (e *CopyFromEncodeError) Unwrap() error {
+	return e.Err
+}
+
+// CopyFromDryRun reads all rows from rowSrc and encodes them as CopyFrom would for tableName and columnNames without
+// copying anything. It can be used to check that data can be loaded before loading it. The types of the columns are
+// described by the server so the connection must be able to query tableName, but no COPY is executed and the table is
+// not modified. It returns the number of rows that would have been copied. If a value cannot be encoded the error is
+// a *CopyFromEncodeError that identifies the row and column.
+func (c *Conn) CopyFromDryRun(ctx context.Context, tableName Identifier, columnNames []string, rowSrc CopyFromSource) (int64, error) {
+	err := c.checkBusy()
+	if err != nil {
+		return 0, err
+	}
+
+	// The columns are described exactly as CopyFrom describes them so the QueryInterceptor is not called.
+	sd, err := c.pgConn.Prepare(ctx, "", fmt.Sprintf("select %s from %s", quoteIdentifiers(columnNames), tableName.Sanitize()), nil)
+	if err != nil {
+		return 0, err
+	}
+
+	var rowCount int64
+	buf := c.wbuf
+	for rowSrc.Next() {
+		values, err := rowSrc.Values()
+		if err != nil {
+			return rowCount, err
+		}
+		if len(values) != len(columnNames) {
+			return rowCount, &CopyFromEncodeError{Row: rowCount, Err: fmt.Errorf("expected %d values, got %d values", len(columnNames), len(values))}
+		}
+
+		for i, val := range values {
+			buf, err = encodePreparedStatementArgument(c.connInfo, buf[:0], sd.Fields[i].DataTypeOID, val)
+			if err != nil {
+				return rowCount, &CopyFromEncodeError{Row: rowCount, Column: columnNames[i], Err: err}
+			}
+		}
+		rowCount++
+	}
+
+	if rowSrc.Err() != nil {
+		return rowCount, rowSrc.Err()
+	}
+
+	return rowCount, nil
+}
+
+// CopyFromOptions are the options of the COPY ... FROM STDIN WITH ( ... ) statement used by CopyFromReader. The zero
+// value uses the text format with the server defaults for all options.
+type CopyFromOptions struct {
+	// Format is "text", "csv", or "binary". Default: "text".
+	Format string
+
+	// Delimiter is the single byte character that separates columns. It is not valid with the binary format.
+	Delimiter string
+
+	// Null is the string that represents a null value. If nil the server default is used: \N in the text format and an
+	// unquoted empty string in the csv format. It is not valid with the binary format.
+	Null *string
+
+	// Header indicates the first line of the input is a header line that is ignored. The text format requires
+	// PostgreSQL 15 or later. It is not valid with the binary format.
+	Header bool
+
+	// Quote is the single byte quoting character. It is only valid with the csv format.
+	Quote string
+
+	// Escape is the single byte character that escapes the quote character. It is only valid with the csv format.
+	Escape string
+
+	// ForceNotNull is the list of columns whose values are never matched against the null string. It is only valid with
+	// the csv format.
+	ForceNotNull []string
+}
+
+func (o *CopyFromOptions) validate() error {
+	switch o.Format {
+	case "", "text", "csv", "binary":
+	default:
+		return fmt.Errorf("invalid copy format %q", o.Format)
+	}
+
+	if o.Format == "binary" {
+		if o.Delimiter != "" || o.Null != nil || o.Header {
+			return errors.New("Delimiter, Null, and Header are not valid with the binary format")
+		}
+	}
+
+	if o.Format != "csv" {
+		if o.Quote != "" || o.Escape != "" || len(o.ForceNotNull) > 0 {
+			return errors.New("Quote, Escape, and ForceNotNull are only valid with the csv format")
+		}
+	}
+
+	for _, c := range []struct {
+		name  string
+		value string
+	}{
+		{"Delimiter", o.Delimiter},
+		{"Quote", o.Quote},
+		{"Escape", o.Escape},
+	} {
+		if c.value != "" && len(c.value) != 1 {
+			return fmt.Errorf("%s must be a single one-byte character, got %q", c.name, c.value)
+		}
+		if c.value == "\x00" {
+			return fmt.Errorf("%s must not be a NUL byte", c.name)
+		}
+	}
+
+	if o.Delimiter == "\r" || o.Delimiter == "\n" {
+		return errors.New("Delimiter cannot be newline or carriage return")
+	}
+
+	if o.Null != nil && strings.IndexByte(*o.Null, 0) != -1 {
+		return errors.New("Null must not contain a NUL byte")
+	}
+
+	if o.Delimiter != "" && o.Null != nil && strings.Contains(*o.Null, o.Delimiter) {
+		return errors.New("Delimiter must not appear in Null")
+	}
+
+	if o.Format == "csv" {
+		delimiter := o.Delimiter
+		if delimiter == "" {
+			delimiter = ","
+		}
+		quote := o.Quote
+		if quote == "" {
+			quote = `"`
+		}
+		if delimiter == quote {
+			return errors.New("Delimiter and Quote must be different")
+		}
+	}
+
+	return nil
+}
+
+// sql returns the WITH clause for o. The string options are quoted with quoteLiteral so a backslash, as in the common
+// \N null string, has the same meaning regardless of the standard_conforming_strings setting. validate must have
+// already succeeded.
+func (o *CopyFromOptions) sql() string {
+	var options []string
+	if o.Format != "" {
+		options = append(options, "format "+o.Format)
+	}
+	if o.Delimiter != "" {
+		options = append(options, "delimiter "+quoteLiteral(o.Delimiter))
+	}
+	if o.Null != nil {
+		options = append(options, "null "+quoteLiteral(*o.Null))
+	}
+	if o.Header {
+		options = append(options, "header true")
+	}
+	if o.Quote != "" {
+		options = append(options, "quote "+quoteLiteral(o.Quote))
+	}
+	if o.Escape != "" {
+		options = append(options, "escape "+quoteLiteral(o.Escape))
+	}
+	if len(o.ForceNotNull) > 0 {
+		quotedColumnNames := make([]string, len(o.ForceNotNull))
+		for i, cn := range o.ForceNotNull {
+			quotedColumnNames[i] = quoteIdentifier(cn)
+		}
+		options = append(options, "force_not_null ("+strings.Join(quotedColumnNames, ", ")+")")
+	}
+
+	if len(options) == 0 {
+		return ""
+	}
+	return " with (" + strings.Join(options, ", ") + ")"
+}
+
+// CopyFromReader uses the PostgreSQL copy protocol to bulk load data that is already in a format COPY understands,
+// such as CSV produced by another system. The data is read from r and sent to the server as is. options controls the
+// format. It returns the number of rows copied and an error. If columnNames is empty all columns of the table are
+// copied in table order.
+//
+// options are validated before anything is sent to the server.
+func (c *Conn) CopyFromReader(ctx context.Context, tableName Identifier, columnNames []string, r io.Reader, options CopyFromOptions) (int64, error) {
+	err := c.checkBusy()
+	if err != nil {
+		return 0, err
+	}
+
+	err = options.validate()
+	if err != nil {
+		return 0, err
+	}
+
+	sql := "copy " + tableName.Sanitize()
+	if len(columnNames) > 0 {
+		quotedColumnNames := make([]string, len(columnNames))
+		for i, cn := range columnNames {
+			quotedColumnNames[i] = quoteIdentifier(cn)
+		}
+		sql += " ( " + strings.Join(quotedColumnNames, ", ") + " )"
+	}
+	sql += " from stdin" + options.sql()
+
+	sql, err = c.interceptSQL(ctx, sql, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	startTime := time.Now()
+
+	commandTag, err := c.pgConn.CopyFrom(ctx, r, sql)
+
+	rowsAffected := commandTag.RowsAffected()
+	if err == nil {
+		if c.shouldLog(LogLevelInfo) {
+			endTime := time.Now()
+			c.log(ctx, LogLevelInfo, "CopyFromReader", map[string]interface{}{"tableName": tableName, "columnNames": columnNames, "time": endTime.Sub(startTime), "rowCount": rowsAffected})
+		}
+	} else if c.shouldLog(LogLevelError) {
+		c.log(ctx, LogLevelError, "CopyFromReader", map[string]interface{}{"err": err, "tableName": tableName, "columnNames": columnNames, "sql": sql})
+	}
+
+	return rowsAffected, err
+}
@@ -2,13 +2,18 @@ package pgx_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/jackc/pgconn"
+	"github.com/jackc/pgio"
+	"github.com/jackc/pgtype"
 	"github.com/jackc/pgx/v4"
 	"github.com/stretchr/testify/require"
 )
@@ -128,6 +133,69 @@ func TestConnCopyFromSliceSmall(t *testing.T) {
 	ensureConnValid(t, conn)
 }
 
+func TestConnCopyFromChanSmall(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	mustExec(t, conn, `create temporary table foo(
+		a int4,
+		b varchar
+	)`)
+
+	inputRows := [][]interface{}{
+		{int32(1), "abc"},
+		{int32(2), "def"},
+		{nil, nil},
+	}
+
+	ch := make(chan []interface{})
+	go func() {
+		for _, row := range inputRows {
+			ch <- row
+		}
+		close(ch)
+	}()
+
+	copyCount, err := conn.CopyFrom(context.Background(), pgx.Identifier{"foo"}, []string{"a", "b"}, pgx.CopyFromChan(context.Background(), ch))
+	require.NoError(t, err)
+	require.EqualValues(t, len(inputRows), copyCount)
+
+	rows, err := conn.Query(context.Background(), "select * from foo order by a nulls last")
+	require.NoError(t, err)
+
+	var outputRows [][]interface{}
+	for rows.Next() {
+		row, err := rows.Values()
+		require.NoError(t, err)
+		outputRows = append(outputRows, row)
+	}
+	require.NoError(t, rows.Err())
+	require.Equal(t, inputRows, outputRows)
+
+	ensureConnValid(t, conn)
+}
+
+func TestCopyFromChanContextCanceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan []interface{}, 1)
+	ch <- []interface{}{int32(1)}
+
+	src := pgx.CopyFromChan(ctx, ch)
+	require.True(t, src.Next())
+	values, err := src.Values()
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{int32(1)}, values)
+
+	cancel()
+	require.False(t, src.Next())
+	require.Equal(t, context.Canceled, src.Err())
+	require.False(t, src.Next())
+}
+
 func TestConnCopyFromLarge(t *testing.T) {
 	t.Parallel()
 
@@ -188,6 +256,54 @@ func TestConnCopyFromLarge(t *testing.T) {
 	ensureConnValid(t, conn)
 }
 
+func TestConnCopyFromRawRows(t *testing.T) {
+	t.Parallel()
+
+	srcConn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, srcConn)
+
+	dstConn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, dstConn)
+
+	mustExec(t, dstConn, `create temporary table foo(
+		a int4,
+		b text,
+		c int8,
+		d numeric,
+		e date
+	)`)
+
+	// c is int4 at the source and int8 at the destination. d is read in the text format.
+	rows, err := srcConn.Query(context.Background(),
+		`select n, 'row ' || n, n * 10, n::numeric / 4, case when n % 2 = 0 then '2021-01-01'::date + n end
+from generate_series(1, 100) n`,
+		pgx.QueryResultFormats{pgx.BinaryFormatCode, pgx.BinaryFormatCode, pgx.BinaryFormatCode, pgx.TextFormatCode, pgx.BinaryFormatCode},
+	)
+	require.NoError(t, err)
+	defer rows.Close()
+
+	copyCount, err := dstConn.CopyFrom(context.Background(), pgx.Identifier{"foo"}, []string{"a", "b", "c", "d", "e"}, pgx.CopyFromRawRows(rows))
+	require.NoError(t, err)
+	require.EqualValues(t, 100, copyCount)
+	rows.Close()
+	require.NoError(t, rows.Err())
+
+	var mismatches int64
+	err = dstConn.QueryRow(context.Background(), `select count(*)
+from foo
+  full join generate_series(1, 100) n on a = n
+where a is null
+  or b <> 'row ' || n
+  or c <> n * 10
+  or d <> n::numeric / 4
+  or e is distinct from case when n % 2 = 0 then '2021-01-01'::date + n end`).Scan(&mismatches)
+	require.NoError(t, err)
+	require.EqualValues(t, 0, mismatches)
+
+	ensureConnValid(t, srcConn)
+	ensureConnValid(t, dstConn)
+}
+
 func TestConnCopyFromEnum(t *testing.T) {
 	t.Parallel()
 
@@ -249,6 +365,72 @@ func TestConnCopyFromEnum(t *testing.T) {
 	ensureConnValid(t, conn)
 }
 
+// copyTextOnlyInt only implements the text format.
+type copyTextOnlyInt int32
+
+func (n copyTextOnlyInt) EncodeText(ci *pgtype.ConnInfo, buf []byte) ([]byte, error) {
+	return strconv.AppendInt(buf, int64(n), 10), nil
+}
+
+// copyPointerInt implements the binary format with a pointer receiver.
+type copyPointerInt int32
+
+func (n *copyPointerInt) EncodeBinary(ci *pgtype.ConnInfo, buf []byte) ([]byte, error) {
+	return pgio.AppendInt32(buf, int32(*n)), nil
+}
+
+func TestConnCopyFromNulls(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	mustExec(t, conn, `create temporary table foo(
+		a int4,
+		b text,
+		c timestamptz,
+		d int4,
+		e int4,
+		f int8
+	)`)
+
+	tzedTime := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	pointerInt := copyPointerInt(3)
+	textOnlyInt := copyTextOnlyInt(4)
+
+	inputRows := [][]interface{}{
+		{int32(1), "abc", tzedTime, copyTextOnlyInt(2), &pointerInt, &textOnlyInt},
+		{nil, nil, nil, nil, nil, nil},
+		{(*int32)(nil), (*string)(nil), (*time.Time)(nil), (*copyTextOnlyInt)(nil), (*copyPointerInt)(nil), (*pgtype.Int8)(nil)},
+		{pgtype.Int4{Status: pgtype.Null}, pgtype.Text{Status: pgtype.Null}, pgtype.Timestamptz{Status: pgtype.Null}, pgtype.Int4{Status: pgtype.Null}, &pgtype.Int4{Status: pgtype.Null}, pgtype.Int8{Status: pgtype.Null}},
+		{int32(5), nil, tzedTime, nil, &pointerInt, nil},
+	}
+
+	copyCount, err := conn.CopyFrom(context.Background(), pgx.Identifier{"foo"}, []string{"a", "b", "c", "d", "e", "f"}, pgx.CopyFromRows(inputRows))
+	require.NoError(t, err)
+	require.EqualValues(t, len(inputRows), copyCount)
+
+	rows, err := conn.Query(context.Background(), "select * from foo")
+	require.NoError(t, err)
+
+	var outputRows [][]interface{}
+	for rows.Next() {
+		row, err := rows.Values()
+		require.NoError(t, err)
+		outputRows = append(outputRows, row)
+	}
+	require.NoError(t, rows.Err())
+
+	require.Len(t, outputRows, len(inputRows))
+	require.Equal(t, []interface{}{int32(1), "abc", tzedTime.Local(), int32(2), int32(3), int64(4)}, outputRows[0])
+	for _, row := range outputRows[1:4] {
+		require.Equal(t, []interface{}{nil, nil, nil, nil, nil, nil}, row)
+	}
+	require.Equal(t, []interface{}{int32(5), nil, tzedTime.Local(), nil, int32(3), nil}, outputRows[4])
+
+	ensureConnValid(t, conn)
+}
+
 func TestConnCopyFromJSON(t *testing.T) {
 	t.Parallel()
 
@@ -604,3 +786,333 @@ func TestConnCopyFromCopyFromSourceErrorEnd(t *testing.T) {
 
 	ensureConnValid(t, conn)
 }
+
+func TestConnCopyFromDryRun(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	mustExec(t, conn, `create temporary table foo(
+		a int2,
+		b text,
+		c date
+	)`)
+
+	inputRows := [][]interface{}{
+		{int16(1), "abc", time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{nil, nil, nil},
+		{int32(2), "def", time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC)},
+	}
+
+	copyCount, err := conn.CopyFromDryRun(context.Background(), pgx.Identifier{"foo"}, []string{"a", "b", "c"}, pgx.CopyFromRows(inputRows))
+	require.NoError(t, err)
+	require.EqualValues(t, 3, copyCount)
+
+	inputRows = append(inputRows, []interface{}{int32(100000), "ghi", nil})
+	copyCount, err = conn.CopyFromDryRun(context.Background(), pgx.Identifier{"foo"}, []string{"a", "b", "c"}, pgx.CopyFromRows(inputRows))
+	var encodeErr *pgx.CopyFromEncodeError
+	require.True(t, errors.As(err, &encodeErr))
+	require.EqualValues(t, 3, encodeErr.Row)
+	require.Equal(t, "a", encodeErr.Column)
+	require.EqualValues(t, 3, copyCount)
+
+	inputRows = [][]interface{}{{int16(1), "abc"}}
+	_, err = conn.CopyFromDryRun(context.Background(), pgx.Identifier{"foo"}, []string{"a", "b", "c"}, pgx.CopyFromRows(inputRows))
+	require.EqualError(t, err, "row 0: expected 3 values, got 2 values")
+
+	// Nothing was copied.
+	var n int64
+	err = conn.QueryRow(context.Background(), "select count(*) from foo").Scan(&n)
+	require.NoError(t, err)
+	require.EqualValues(t, 0, n)
+
+	ensureConnValid(t, conn)
+}
+
+func TestConnCopyFromDryRunDoesNotCallQueryInterceptor(t *testing.T) {
+	t.Parallel()
+
+	var intercepted []string
+	config := mustParseConfig(t, os.Getenv("PGX_TEST_DATABASE"))
+	config.QueryInterceptor = func(ctx context.Context, sql string, args []interface{}) (string, error) {
+		intercepted = append(intercepted, sql)
+		return sql, nil
+	}
+
+	conn := mustConnect(t, config)
+	defer closeConn(t, conn)
+
+	mustExec(t, conn, `create temporary table foo(a int4)`)
+	intercepted = nil
+
+	copyCount, err := conn.CopyFromDryRun(context.Background(), pgx.Identifier{"foo"}, []string{"a"}, pgx.CopyFromRows([][]interface{}{{int32(1)}}))
+	require.NoError(t, err)
+	require.EqualValues(t, 1, copyCount)
+	require.Empty(t, intercepted)
+
+	ensureConnValid(t, conn)
+}
+
+func TestConnCopyFromReaderCSV(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	mustExec(t, conn, `create temporary table foo(
+		a int4,
+		b text,
+		c text
+	)`)
+
+	input := "a;b;c\n1;'x;y';\n2;;NULL\n"
+	null := "NULL"
+	copyCount, err := conn.CopyFromReader(context.Background(), pgx.Identifier{"foo"}, []string{"a", "b", "c"}, strings.NewReader(input), pgx.CopyFromOptions{
+		Format:       "csv",
+		Delimiter:    ";",
+		Null:         &null,
+		Header:       true,
+		Quote:        "'",
+		ForceNotNull: []string{"b", "c"},
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, 2, copyCount)
+
+	rows, err := conn.Query(context.Background(), "select a, b, c from foo order by a")
+	require.NoError(t, err)
+
+	var results [][]interface{}
+	for rows.Next() {
+		var a int32
+		var b, c pgtype.Text
+		require.NoError(t, rows.Scan(&a, &b, &c))
+		results = append(results, []interface{}{a, b, c})
+	}
+	require.NoError(t, rows.Err())
+
+	require.Equal(t, [][]interface{}{
+		{int32(1), pgtype.Text{String: "x;y", Status: pgtype.Present}, pgtype.Text{String: "", Status: pgtype.Present}},
+		// NULL is forced to not null for c so it is the literal string.
+		{int32(2), pgtype.Text{String: "", Status: pgtype.Present}, pgtype.Text{String: "NULL", Status: pgtype.Present}},
+	}, results)
+
+	ensureConnValid(t, conn)
+}
+
+func TestConnCopyFromReaderText(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	mustExec(t, conn, `create temporary table foo(
+		a int4,
+		b text
+	)`)
+
+	copyCount, err := conn.CopyFromReader(context.Background(), pgx.Identifier{"foo"}, nil, strings.NewReader("1\tfoo\n2\t\\N\n"), pgx.CopyFromOptions{})
+	require.NoError(t, err)
+	require.EqualValues(t, 2, copyCount)
+
+	var nullCount int64
+	err = conn.QueryRow(context.Background(), "select count(*) from foo where b is null").Scan(&nullCount)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, nullCount)
+
+	ensureConnValid(t, conn)
+}
+
+func TestConnCopyFromReaderBackslashOptionsWithoutStandardConformingStrings(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	mustExec(t, conn, "set standard_conforming_strings = off")
+	mustExec(t, conn, `create temporary table foo(
+		a int4,
+		b text
+	)`)
+
+	null := `\N`
+	copyCount, err := conn.CopyFromReader(context.Background(), pgx.Identifier{"foo"}, nil, strings.NewReader("1,\"a\\\"b\"\n2,\\N\n"), pgx.CopyFromOptions{
+		Format: "csv",
+		Null:   &null,
+		Escape: `\`,
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, 2, copyCount)
+
+	var b pgtype.Text
+	err = conn.QueryRow(context.Background(), "select b from foo where a = 1").Scan(&b)
+	require.NoError(t, err)
+	require.Equal(t, pgtype.Text{String: `a"b`, Status: pgtype.Present}, b)
+
+	err = conn.QueryRow(context.Background(), "select b from foo where a = 2").Scan(&b)
+	require.NoError(t, err)
+	require.Equal(t, pgtype.Null, b.Status)
+
+	ensureConnValid(t, conn)
+}
+
+func TestConnCopyFromReaderInvalidOptions(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	null := ""
+	nulByte := "a\x00"
+	for i, options := range []pgx.CopyFromOptions{
+		{Format: "xml"},
+		{Format: "text", Quote: "'"},
+		{Escape: "\\"},
+		{ForceNotNull: []string{"a"}},
+		{Format: "binary", Delimiter: ","},
+		{Format: "binary", Null: &null},
+		{Format: "binary", Header: true},
+		{Format: "csv", Delimiter: ",,"},
+		{Format: "csv", Delimiter: "\n"},
+		{Format: "csv", Delimiter: "'", Quote: "'"},
+		{Format: "csv", Delimiter: `"`},
+		{Format: "csv", Escape: "\x00"},
+		{Null: &nulByte},
+	} {
+		_, err := conn.CopyFromReader(context.Background(), pgx.Identifier{"foo"}, nil, strings.NewReader(""), options)
+		require.Errorf(t, err, "%d", i)
+	}
+
+	ensureConnValid(t, conn)
+}
+
+func TestConnCopyFromFailureLogsRowsSent(t *testing.T) {
+	t.Parallel()
+
+	logger := &testLogger{}
+	config := mustParseConfig(t, os.Getenv("PGX_TEST_DATABASE"))
+	config.Logger = logger
+	config.LogLevel = pgx.LogLevelError
+
+	conn := mustConnect(t, config)
+	defer closeConn(t, conn)
+
+	mustExec(t, conn, `create temporary table foo(
+		a bytea not null
+	)`)
+
+	_, err := conn.CopyFrom(context.Background(), pgx.Identifier{"foo"}, []string{"a"}, &clientFailSource{})
+	require.Error(t, err)
+
+	var copyLog *testLog
+	for i := range logger.logs {
+		if logger.logs[i].msg == "CopyFrom" {
+			copyLog = &logger.logs[i]
+		}
+	}
+	require.NotNil(t, copyLog)
+	require.Equal(t, pgx.LogLevelError, copyLog.lvl)
+	require.Contains(t, copyLog.data, "time")
+
+	// Each row is larger than the write buffer so the two rows before the failing row were sent.
+	require.EqualValues(t, 2, copyLog.data["rowsSent"])
+
+	ensureConnValid(t, conn)
+}
+
+func TestCopyFromUpsert(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	mustExec(t, conn, `create temporary table foo(
+		a int4,
+		b int4,
+		c text not null,
+		primary key (a, b)
+	)`)
+	mustExec(t, conn, "insert into foo(a, b, c) values (1, 1, 'old'), (1, 2, 'old')")
+
+	inputRows := [][]interface{}{
+		{int32(1), int32(1), "new"},
+		{int32(2), int32(1), "new"},
+	}
+
+	// DO NOTHING
+	n, err := pgx.CopyFromUpsert(context.Background(), conn, pgx.Identifier{"foo"}, []string{"a", "b", "c"}, pgx.CopyFromRows(inputRows), []string{"a", "b"}, nil)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, n)
+
+	var c string
+	err = conn.QueryRow(context.Background(), "select c from foo where a = 1 and b = 1").Scan(&c)
+	require.NoError(t, err)
+	require.Equal(t, "old", c)
+
+	// DO UPDATE
+	n, err = pgx.CopyFromUpsert(context.Background(), conn, pgx.Identifier{"foo"}, []string{"a", "b", "c"}, pgx.CopyFromRows(inputRows), []string{"a", "b"}, []string{"c"})
+	require.NoError(t, err)
+	require.EqualValues(t, 2, n)
+
+	rows, err := conn.Query(context.Background(), "select a, b, c from foo order by a, b")
+	require.NoError(t, err)
+	var results [][]interface{}
+	for rows.Next() {
+		values, err := rows.Values()
+		require.NoError(t, err)
+		results = append(results, values)
+	}
+	require.NoError(t, rows.Err())
+	require.Equal(t, [][]interface{}{
+		{int32(1), int32(1), "new"},
+		{int32(1), int32(2), "old"},
+		{int32(2), int32(1), "new"},
+	}, results)
+
+	// The temporary table was dropped.
+	var tempTableCount int64
+	err = conn.QueryRow(context.Background(), "select count(*) from pg_class where relname like 'pgx_upsert_%' and relpersistence = 't'").Scan(&tempTableCount)
+	require.NoError(t, err)
+	require.EqualValues(t, 0, tempTableCount)
+
+	ensureConnValid(t, conn)
+}
+
+func TestCopyFromUpsertRollsBackOnFailure(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	mustExec(t, conn, `create temporary table foo(
+		a int4 primary key,
+		b text not null
+	)`)
+
+	tx, err := conn.Begin(context.Background())
+	require.NoError(t, err)
+	defer tx.Rollback(context.Background())
+
+	_, err = tx.Exec(context.Background(), "insert into foo(a, b) values (1, 'kept')")
+	require.NoError(t, err)
+
+	inputRows := [][]interface{}{
+		{int32(1), "new"},
+		{int32(2), nil}, // violates not null on the merge
+	}
+	_, err = pgx.CopyFromUpsert(context.Background(), tx, pgx.Identifier{"foo"}, []string{"a", "b"}, pgx.CopyFromRows(inputRows), []string{"a"}, []string{"b"})
+	require.Error(t, err)
+
+	// Only the pseudo nested transaction was rolled back.
+	var b string
+	err = tx.QueryRow(context.Background(), "select b from foo where a = 1").Scan(&b)
+	require.NoError(t, err)
+	require.Equal(t, "kept", b)
+
+	require.NoError(t, tx.Commit(context.Background()))
+
+	_, err = pgx.CopyFromUpsert(context.Background(), conn, pgx.Identifier{"foo"}, []string{"a", "b"}, pgx.CopyFromRows(nil), nil, []string{"b"})
+	require.Error(t, err)
+
+	ensureConnValid(t, conn)
+}
@@ -0,0 +1,93 @@
+package pgx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+var copyFromUpsertTableNum int64
+
+// CopyFromUpsert bulk inserts rows from rowSrc into tableName, resolving conflicts with INSERT ... ON CONFLICT. It is
+// much faster than individual upserts for large numbers of rows. It returns the number of rows inserted or updated.
+//
+// The rows are copied with CopyFrom into a uniquely named temporary table with the types of columnNames in tableName.
+// They are then merged into tableName with a single INSERT ... SELECT ... ON CONFLICT (conflictColumns). If
+// updateColumns is empty conflicting rows are skipped with DO NOTHING. Otherwise, they are updated with DO UPDATE SET
+// for each of updateColumns to the value being inserted. conflictColumns may name several columns for a composite
+// unique constraint. It may only be empty with DO NOTHING, in which case conflicts with any constraint are skipped.
+//
+// db is used to begin a transaction (or a pseudo nested transaction if db is a Tx) so all steps succeed or fail
+// together. The temporary table is dropped before the transaction is committed.
+func CopyFromUpsert(ctx context.Context, db interface {
+	Begin(ctx context.Context) (Tx, error)
+}, tableName Identifier, columnNames []string, rowSrc CopyFromSource, conflictColumns []string, updateColumns []string) (int64, error) {
+	if len(columnNames) == 0 {
+		return 0, errors.New("CopyFromUpsert requires at least one column")
+	}
+	if len(updateColumns) > 0 && len(conflictColumns) == 0 {
+		return 0, errors.New("CopyFromUpsert requires conflictColumns to update conflicting rows")
+	}
+
+	quotedColumnNames := quoteIdentifiers(columnNames)
+	tempTableName := "pgx_upsert_" + strconv.FormatInt(atomic.AddInt64(&copyFromUpsertTableNum, 1), 10)
+	quotedTempTableName := quoteIdentifier(tempTableName)
+
+	insertSQL := fmt.Sprintf("insert into %s ( %s ) select %s from %s on conflict", tableName.Sanitize(), quotedColumnNames, quotedColumnNames, quotedTempTableName)
+	if len(conflictColumns) > 0 {
+		insertSQL += " ( " + quoteIdentifiers(conflictColumns) + " )"
+	}
+	if len(updateColumns) == 0 {
+		insertSQL += " do nothing"
+	} else {
+		assignments := make([]string, len(updateColumns))
+		for i, cn := range updateColumns {
+			assignments[i] = quoteIdentifier(cn) + " = excluded." + quoteIdentifier(cn)
+		}
+		insertSQL += " do update set " + strings.Join(assignments, ", ")
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, fmt.Sprintf("create temporary table %s on commit drop as select %s from %s with no data", quotedTempTableName, quotedColumnNames, tableName.Sanitize()))
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.CopyFrom(ctx, Identifier{tempTableName}, columnNames, rowSrc)
+	if err != nil {
+		return 0, err
+	}
+
+	commandTag, err := tx.Exec(ctx, insertSQL)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(ctx, "drop table "+quotedTempTableName)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return commandTag.RowsAffected(), nil
+}
+
+func quoteIdentifiers(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = quoteIdentifier(name)
+	}
+	return strings.Join(quoted, ", ")
+}
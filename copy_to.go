@@ -0,0 +1,356 @@
+package pgx
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgproto3/v2"
+	"github.com/jackc/pgtype"
+)
+
+var copyBinarySignature = []byte("PGCOPY\n\377\r\n\000")
+
+// CopyToRows uses the PostgreSQL copy protocol to stream the results of sql out of the server. sql must be a query
+// that could be used with COPY (query) TO STDOUT, such as a select statement. A trailing semicolon is removed, even if it
+// is followed by comments.
+// CopyToRows does not support query arguments.
+//
+// The rows are transferred in the binary copy format and decoded with the connection's ConnInfo. This has less
+// protocol overhead per row than Query, which makes it well suited to exporting large result sets. The returned Rows
+// behaves like the Rows returned by Query. If it is closed before all rows have been read, a cancel request is sent to
+// the server and the remaining copy data is discarded. The connection remains usable. As with any PostgreSQL cancel
+// request, there is no guarantee when the server acts on it, so a cancel for a copy that was just about to finish
+// may instead affect a statement that is sent immediately afterward.
+func (c *Conn) CopyToRows(ctx context.Context, sql string) (Rows, error) {
+	rows := &copyToRows{
+		ctx:       ctx,
+		conn:      c,
+		sql:       sql,
+		startTime: time.Now(),
+		doneChan:  make(chan struct{}),
+	}
+
+	err := c.checkBusy()
+	if err != nil {
+		rows.fatal(err)
+		return rows, err
+	}
+
+	// The query is intercepted rather than the COPY statement so the statement used to describe the results is the one
+	// that is copied.
+	sql, err = c.interceptSQL(ctx, sql, nil)
+	if err != nil {
+		rows.fatal(err)
+		return rows, err
+	}
+	sql = trimTrailingSemicolon(sql)
+	// The newline ends a trailing -- comment in sql.
+	copySQL := "copy (" + sql + "\n) to stdout binary"
+
+	sd, err := c.pgConn.Prepare(ctx, "", sql, nil)
+	if err != nil {
+		rows.fatal(err)
+		return rows, err
+	}
+	if len(sd.ParamOIDs) != 0 {
+		err = fmt.Errorf("CopyToRows does not support query arguments, got %d parameters", len(sd.ParamOIDs))
+		rows.fatal(err)
+		return rows, err
+	}
+
+	rows.fieldDescriptions = make([]pgproto3.FieldDescription, len(sd.Fields))
+	copy(rows.fieldDescriptions, sd.Fields)
+	for i := range rows.fieldDescriptions {
+		rows.fieldDescriptions[i].Format = BinaryFormatCode
+	}
+
+	r, w := io.Pipe()
+	rows.r = r
+	rows.br = bufio.NewReader(r)
+
+	go func() {
+		defer close(rows.doneChan)
+		rows.copyCommandTag, rows.copyErr = c.pgConn.CopyTo(ctx, w, copySQL)
+		w.CloseWithError(rows.copyErr)
+	}()
+
+	err = rows.readHeader()
+	if err != nil {
+		rows.readDone = true
+		rows.fatal(err)
+		return rows, rows.err
+	}
+
+	return rows, nil
+}
+
+// copyToRows implements the Rows interface for Conn.CopyToRows.
+// trimTrailingSemicolon returns sql without the semicolon that ends its last statement if that semicolon is only
+// followed by whitespace and comments. String literals, quoted identifiers, and dollar-quoted strings are skipped as in
+// NamedArgs.
+func trimTrailingSemicolon(sql string) string {
+	semicolon := -1
+	for pos := 0; pos < len(sql); {
+		r, width := utf8.DecodeRuneInString(sql[pos:])
+
+		switch {
+		case r == ';':
+			semicolon = pos
+			pos += width
+		case unicode.IsSpace(r):
+			pos += width
+		case r == '-' && strings.HasPrefix(sql[pos:], "--"):
+			end := strings.IndexByte(sql[pos:], '\n')
+			if end == -1 {
+				pos = len(sql)
+			} else {
+				pos += end + 1
+			}
+		case r == '/' && strings.HasPrefix(sql[pos:], "/*"):
+			pos = skipBlockComment(sql, pos)
+		default:
+			semicolon = -1
+			switch {
+			case r == '\'':
+				escapes := pos > 0 && (sql[pos-1] == 'E' || sql[pos-1] == 'e') && (pos == 1 || !isNamedArgRune(rune(sql[pos-2])))
+				pos = skipQuoted(sql, pos, '\'', escapes)
+			case r == '"':
+				pos = skipQuoted(sql, pos, '"', false)
+			case r == '$' && (pos == 0 || !isNamedArgRune(rune(sql[pos-1]))):
+				pos = skipDollarQuoted(sql, pos)
+			default:
+				pos += width
+			}
+		}
+	}
+
+	if semicolon == -1 {
+		return sql
+	}
+	return sql[:semicolon] + sql[semicolon+1:]
+}
+
+type copyToRows struct {
+	ctx       context.Context
+	conn      *Conn
+	sql       string
+	startTime time.Time
+
+	fieldDescriptions []pgproto3.FieldDescription
+	values            [][]byte
+	rowCount          int
+	commandTag        pgconn.CommandTag
+	err               error
+	closed            bool
+	scanPlans         []pgtype.ScanPlan
+
+	r        *io.PipeReader
+	br       *bufio.Reader
+	readDone bool // true once the copy trailer has been read or reading failed
+
+	// copyCommandTag and copyErr are set by the goroutine running CopyTo before doneChan is closed.
+	doneChan       chan struct{}
+	copyCommandTag pgconn.CommandTag
+	copyErr        error
+	canceled       bool
+}
+
+func (rows *copyToRows) readHeader() error {
+	header := make([]byte, len(copyBinarySignature)+8)
+	_, err := io.ReadFull(rows.br, header)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(header[:len(copyBinarySignature)], copyBinarySignature) {
+		return errors.New("invalid binary copy signature")
+	}
+
+	extensionLen := int64(int32(binary.BigEndian.Uint32(header[len(copyBinarySignature)+4:])))
+	if extensionLen < 0 {
+		return errors.New("invalid binary copy header extension length")
+	}
+	_, err = io.CopyN(ioutil.Discard, rows.br, extensionLen)
+	return err
+}
+
+// readRow reads the next tuple. It returns false when the trailer is reached.
+func (rows *copyToRows) readRow() (bool, error) {
+	buf := make([]byte, 4)
+	_, err := io.ReadFull(rows.br, buf[:2])
+	if err != nil {
+		return false, err
+	}
+
+	fieldCount := int16(binary.BigEndian.Uint16(buf[:2]))
+	if fieldCount == -1 {
+		return false, nil
+	}
+	if int(fieldCount) != len(rows.fieldDescriptions) {
+		return false, fmt.Errorf("expected %d fields in copy row, got %d", len(rows.fieldDescriptions), fieldCount)
+	}
+
+	values := make([][]byte, fieldCount)
+	for i := range values {
+		_, err := io.ReadFull(rows.br, buf)
+		if err != nil {
+			return false, err
+		}
+
+		fieldLen := int32(binary.BigEndian.Uint32(buf))
+		if fieldLen == -1 {
+			continue
+		}
+		if fieldLen < 0 {
+			return false, fmt.Errorf("invalid field length %d in copy row", fieldLen)
+		}
+
+		values[i] = make([]byte, fieldLen)
+		_, err = io.ReadFull(rows.br, values[i])
+		if err != nil {
+			return false, err
+		}
+	}
+
+	rows.values = values
+	return true, nil
+}
+
+func (rows *copyToRows) FieldDescriptions() []pgproto3.FieldDescription {
+	return rows.fieldDescriptions
+}
+
+func (rows *copyToRows) Close() {
+	if rows.closed {
+		return
+	}
+
+	rows.closed = true
+
+	if rows.r != nil {
+		if !rows.readDone {
+			// The caller stopped reading early. Ask the server to abort the copy.
+			rows.canceled = true
+			cancelCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+			rows.conn.pgConn.CancelRequest(cancelCtx)
+			cancel()
+		}
+
+		// Discard anything that has not been read so CopyTo can finish and unlock the connection.
+		io.Copy(ioutil.Discard, rows.r)
+		<-rows.doneChan
+		rows.r.Close()
+
+		rows.commandTag = rows.copyCommandTag
+		if rows.err == nil && !(rows.canceled && isQueryCanceled(rows.copyErr)) {
+			rows.err = rows.copyErr
+		}
+	}
+
+	if rows.err == nil {
+		if rows.conn.shouldLog(LogLevelInfo) {
+			endTime := time.Now()
+			rows.conn.log(rows.ctx, LogLevelInfo, "CopyToRows", map[string]interface{}{"sql": rows.sql, "time": endTime.Sub(rows.startTime), "rowCount": rows.rowCount})
+		}
+	} else if rows.conn.shouldLog(LogLevelError) {
+		rows.conn.log(rows.ctx, LogLevelError, "CopyToRows", map[string]interface{}{"err": rows.err, "sql": rows.sql})
+	}
+}
+
+func (rows *copyToRows) CommandTag() pgconn.CommandTag {
+	return rows.commandTag
+}
+
+func (rows *copyToRows) Err() error {
+	return rows.err
+}
+
+func (rows *copyToRows) fatal(err error) {
+	if rows.err != nil {
+		return
+	}
+
+	rows.err = err
+	rows.Close()
+}
+
+func (rows *copyToRows) Next() bool {
+	if rows.closed {
+		return false
+	}
+
+	ok, err := rows.readRow()
+	if err != nil {
+		// An error from CopyTo closes the pipe with that error so it is what readRow returns.
+		rows.readDone = true
+		rows.fatal(err)
+		return false
+	}
+
+	if !ok {
+		rows.readDone = true
+		rows.Close()
+		return false
+	}
+
+	rows.rowCount++
+	return true
+}
+
+func (rows *copyToRows) Scan(dest ...interface{}) error {
+	ci := rows.conn.connInfo
+	fieldDescriptions := rows.fieldDescriptions
+	values := rows.values
+
+	if len(fieldDescriptions) != len(dest) {
+		err := fmt.Errorf("number of field descriptions must equal number of destinations, got %d and %d", len(fieldDescriptions), len(dest))
+		rows.fatal(err)
+		return err
+	}
+
+	if rows.scanPlans == nil {
+		rows.scanPlans = planScans(ci, fieldDescriptions, dest)
+	}
+
+	err := scanValues(ci, fieldDescriptions, values, rows.scanPlans, dest)
+	if err != nil {
+		rows.fatal(err)
+		return err
+	}
+
+	return nil
+}
+
+func (rows *copyToRows) Values() ([]interface{}, error) {
+	if rows.closed {
+		return nil, errors.New("rows is closed")
+	}
+
+	values, err := decodeRowValues(rows.conn.connInfo, rows.fieldDescriptions, rows.values)
+	if err != nil {
+		rows.fatal(err)
+		return nil, rows.Err()
+	}
+
+	return values, nil
+}
+
+func (rows *copyToRows) RawValues() [][]byte {
+	return rows.values
+}
+
+func isQueryCanceled(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "57014"
+}
@@ -0,0 +1,143 @@
+package pgx_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnCopyToRows(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	rows, err := conn.CopyToRows(context.Background(), "select n, 'row ' || n, case when n % 2 = 0 then null else n::int8 end, '2021-07-10'::date from generate_series(1, 1000) n")
+	require.NoError(t, err)
+
+	fieldDescriptions := rows.FieldDescriptions()
+	require.Len(t, fieldDescriptions, 4)
+
+	var rowCount int32
+	for rows.Next() {
+		rowCount++
+
+		var n int32
+		var s string
+		var nullable *int64
+		var d time.Time
+		err := rows.Scan(&n, &s, &nullable, &d)
+		require.NoError(t, err)
+
+		assert.Equal(t, rowCount, n)
+		assert.Equal(t, fmt.Sprintf("row %d", n), s)
+		if n%2 == 0 {
+			assert.Nil(t, nullable)
+		} else {
+			require.NotNil(t, nullable)
+			assert.EqualValues(t, n, *nullable)
+		}
+		assert.Equal(t, time.Date(2021, 7, 10, 0, 0, 0, 0, time.UTC), d)
+	}
+	require.NoError(t, rows.Err())
+	assert.EqualValues(t, 1000, rowCount)
+	assert.Equal(t, "COPY 1000", string(rows.CommandTag()))
+
+	ensureConnValid(t, conn)
+}
+
+func TestConnCopyToRowsValues(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	rows, err := conn.CopyToRows(context.Background(), "select 1::int4, 'foo'::text, null::text")
+	require.NoError(t, err)
+
+	require.True(t, rows.Next())
+	values, err := rows.Values()
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{int32(1), "foo", nil}, values)
+
+	require.False(t, rows.Next())
+	require.NoError(t, rows.Err())
+
+	ensureConnValid(t, conn)
+}
+
+func TestConnCopyToRowsTrailingCommentAndSemicolon(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	for _, sql := range []string{
+		"select 1::int4 -- trailing comment",
+		"select 1::int4;",
+		"select 1::int4 ; \n",
+		"select 1::int4; -- done",
+		"select 1::int4; /* done */\n",
+		"select 1::int4 /* ; */",
+		"select length(';')::int4;",
+	} {
+		rows, err := conn.CopyToRows(context.Background(), sql)
+		require.NoError(t, err, sql)
+
+		var n int32
+		require.True(t, rows.Next(), sql)
+		require.NoError(t, rows.Scan(&n), sql)
+		assert.EqualValues(t, 1, n, sql)
+		require.False(t, rows.Next(), sql)
+		require.NoError(t, rows.Err(), sql)
+	}
+
+	ensureConnValid(t, conn)
+}
+
+func TestConnCopyToRowsCloseEarly(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	rows, err := conn.CopyToRows(context.Background(), "select n from generate_series(1, 10000000) n")
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		require.True(t, rows.Next())
+	}
+	rows.Close()
+	require.NoError(t, rows.Err())
+	assert.False(t, conn.IsClosed())
+
+	ensureConnValid(t, conn)
+}
+
+func TestConnCopyToRowsQueryError(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	rows, err := conn.CopyToRows(context.Background(), "select 1/(n - 5) from generate_series(1, 10) n")
+	require.NoError(t, err)
+
+	for rows.Next() {
+	}
+	var pgErr *pgconn.PgError
+	require.ErrorAs(t, rows.Err(), &pgErr)
+	assert.Equal(t, "22012", pgErr.Code)
+
+	rows, err = conn.CopyToRows(context.Background(), "select $1::int4")
+	require.Error(t, err)
+	assert.Error(t, rows.Err())
+
+	ensureConnValid(t, conn)
+}
@@ -0,0 +1,128 @@
+package pgx
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+)
+
+// ErrCursorOutsideTx occurs when QueryCursor is called on a connection that is not in a transaction.
+var ErrCursorOutsideTx = errors.New("cursor must be declared in a transaction")
+
+var cursorNum int64
+
+// Cursor is a server-side cursor created by Conn.QueryCursor. It allows reading the results of a query in chunks of a
+// chosen size so a large result set does not need to be read at once. A Cursor is only valid within the transaction
+// in which it was declared.
+type Cursor struct {
+	conn       *Conn
+	name       string
+	quotedName string
+	exhausted  bool
+	closed     bool
+}
+
+// QueryCursor declares a server-side cursor named name for sql and args in the current transaction. If name is empty
+// a unique name is generated. Rows are then read with Fetch. sql must be a select or values statement. QueryCursor
+// returns ErrCursorOutsideTx if the connection is not in a transaction. In a failed transaction it returns the
+// server's error that the current transaction is aborted.
+//
+// A cursor is closed automatically when the transaction ends, so a caller that abandons iteration early does not leak
+// it. Close can be used to release it earlier.
+func (c *Conn) QueryCursor(ctx context.Context, name, sql string, args ...interface{}) (*Cursor, error) {
+	// In a failed transaction the DECLARE is sent anyway so the server reports that the transaction is aborted.
+	if txStatus := c.pgConn.TxStatus(); txStatus != 'T' && txStatus != 'E' {
+		return nil, ErrCursorOutsideTx
+	}
+
+	if name == "" {
+		name = "pgx_cursor_" + strconv.FormatInt(atomic.AddInt64(&cursorNum, 1), 10)
+	}
+	quotedName := quoteIdentifier(name)
+
+	// Each cursor name produces distinct SQL so there is no point in caching these statements.
+	args = append([]interface{}{QuerySkipStatementCache(true)}, args...)
+	_, err := c.Exec(ctx, "declare "+quotedName+" no scroll cursor for "+sql, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cursor{conn: c, name: name, quotedName: quotedName}, nil
+}
+
+// Name returns the name of the cursor.
+func (cur *Cursor) Name() string {
+	return cur.name
+}
+
+// Fetch reads up to n rows from the cursor. The returned Rows must be closed before the connection is used again. It
+// returns fewer than n rows, possibly none, when the end of the results is reached. Exhausted reports whether that
+// has happened once the returned Rows is closed.
+func (cur *Cursor) Fetch(ctx context.Context, n int) (Rows, error) {
+	if cur.closed {
+		err := errors.New("cursor is closed")
+		return &connRows{err: err, closed: true}, err
+	}
+	if n < 1 {
+		err := errors.New("n must be greater than 0")
+		return &connRows{err: err, closed: true}, err
+	}
+
+	rows, err := cur.conn.Query(ctx, "fetch forward "+strconv.Itoa(n)+" from "+cur.quotedName, QuerySkipStatementCache(true))
+	return &cursorRows{Rows: rows, cursor: cur, n: n}, err
+}
+
+// Exhausted returns true once a Fetch has returned fewer rows than were requested.
+func (cur *Cursor) Exhausted() bool {
+	return cur.exhausted
+}
+
+// Close closes the cursor. It is safe to call Close multiple times. Close does nothing if the connection is no longer
+// in a transaction as the cursor was already closed when its transaction ended.
+func (cur *Cursor) Close(ctx context.Context) error {
+	if cur.closed {
+		return nil
+	}
+	cur.closed = true
+
+	// A cursor does not outlive its transaction and no statements can run in a failed transaction.
+	if cur.conn.pgConn.TxStatus() != 'T' {
+		return nil
+	}
+
+	_, err := cur.conn.Exec(ctx, "close "+cur.quotedName)
+	return err
+}
+
+// cursorRows wraps the Rows returned by Cursor.Fetch to detect the end of the cursor.
+type cursorRows struct {
+	Rows
+	cursor *Cursor
+	n      int
+	done   bool
+}
+
+func (rows *cursorRows) Next() bool {
+	if rows.Rows.Next() {
+		return true
+	}
+	rows.finish()
+	return false
+}
+
+func (rows *cursorRows) Close() {
+	rows.Rows.Close()
+	rows.finish()
+}
+
+func (rows *cursorRows) finish() {
+	if rows.done {
+		return
+	}
+	rows.done = true
+
+	if rows.Err() == nil && rows.CommandTag().RowsAffected() < int64(rows.n) {
+		rows.cursor.exhausted = true
+	}
+}
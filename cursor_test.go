@@ -0,0 +1,121 @@
+package pgx_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnQueryCursor(t *testing.T) {
+	t.Parallel()
+
+	testWithAndWithoutPreferSimpleProtocol(t, func(t *testing.T, conn *pgx.Conn) {
+		tx, err := conn.Begin(context.Background())
+		require.NoError(t, err)
+		defer tx.Rollback(context.Background())
+
+		cur, err := conn.QueryCursor(context.Background(), "", "select n from generate_series(1, $1) n", 25)
+		require.NoError(t, err)
+		defer cur.Close(context.Background())
+
+		var chunks [][]int32
+		for !cur.Exhausted() {
+			rows, err := cur.Fetch(context.Background(), 10)
+			require.NoError(t, err)
+
+			var chunk []int32
+			for rows.Next() {
+				var n int32
+				require.NoError(t, rows.Scan(&n))
+				chunk = append(chunk, n)
+			}
+			require.NoError(t, rows.Err())
+
+			chunks = append(chunks, chunk)
+		}
+
+		require.Len(t, chunks, 3)
+		require.Len(t, chunks[0], 10)
+		require.Len(t, chunks[1], 10)
+		require.Equal(t, []int32{21, 22, 23, 24, 25}, chunks[2])
+
+		require.NoError(t, cur.Close(context.Background()))
+		require.NoError(t, cur.Close(context.Background()))
+
+		// The cursor no longer exists.
+		_, err = tx.Exec(context.Background(), "close "+cur.Name())
+		require.Error(t, err)
+	})
+}
+
+func TestConnQueryCursorAbandoned(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	tx, err := conn.Begin(context.Background())
+	require.NoError(t, err)
+
+	cur, err := conn.QueryCursor(context.Background(), "abandoned", "select n from generate_series(1, 100) n")
+	require.NoError(t, err)
+	require.Equal(t, "abandoned", cur.Name())
+
+	rows, err := cur.Fetch(context.Background(), 10)
+	require.NoError(t, err)
+	require.True(t, rows.Next())
+	rows.Close()
+	require.False(t, cur.Exhausted())
+
+	require.NoError(t, tx.Commit(context.Background()))
+
+	// The cursor was closed with the transaction so the name can be reused.
+	tx, err = conn.Begin(context.Background())
+	require.NoError(t, err)
+	defer tx.Rollback(context.Background())
+
+	_, err = conn.QueryCursor(context.Background(), "abandoned", "select 1")
+	require.NoError(t, err)
+
+	ensureConnValid(t, conn)
+}
+
+func TestConnQueryCursorOutsideTx(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	_, err := conn.QueryCursor(context.Background(), "", "select 1")
+	require.True(t, errors.Is(err, pgx.ErrCursorOutsideTx))
+
+	ensureConnValid(t, conn)
+}
+
+func TestConnQueryCursorInFailedTx(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	tx, err := conn.Begin(context.Background())
+	require.NoError(t, err)
+	defer tx.Rollback(context.Background())
+
+	_, err = tx.Exec(context.Background(), "select 1/0")
+	require.Error(t, err)
+
+	_, err = conn.QueryCursor(context.Background(), "", "select 1")
+	require.False(t, errors.Is(err, pgx.ErrCursorOutsideTx))
+	var pgErr *pgconn.PgError
+	require.True(t, errors.As(err, &pgErr), err)
+	require.Equal(t, "25P02", pgErr.Code)
+
+	require.NoError(t, tx.Rollback(context.Background()))
+	ensureConnValid(t, conn)
+}
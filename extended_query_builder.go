@@ -15,10 +15,17 @@ type extendedQueryBuilder struct {
 	resultFormats   []int16
 
 	resetCount int
+
+	textFormatOIDs map[uint32]struct{} // see ConnConfig.TextFormatOIDs
 }
 
 func (eqb *extendedQueryBuilder) AppendParam(ci *pgtype.ConnInfo, oid uint32, arg interface{}) error {
 	f := chooseParameterFormatCode(ci, oid, arg)
+	if f == BinaryFormatCode {
+		if _, ok := eqb.textFormatOIDs[oid]; ok && canEncodeText(arg) {
+			f = TextFormatCode
+		}
+	}
 	eqb.paramFormats = append(eqb.paramFormats, f)
 
 	v, err := eqb.encodeExtendedParamValue(ci, oid, f, arg)
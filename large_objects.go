@@ -16,6 +16,10 @@ type LargeObjects struct {
 
 type LargeObjectMode int32
 
+// defaultLargeObjectChunkSize is the default maximum number of bytes transferred by a single lo_read or lo_write call.
+// It is just under the 1GB limit of a bytea value and of a protocol message.
+const defaultLargeObjectChunkSize = 1024*1024*1024 - 1024
+
 const (
 	LargeObjectModeWrite LargeObjectMode = 0x20000
 	LargeObjectModeRead  LargeObjectMode = 0x40000
@@ -35,7 +39,7 @@ func (o *LargeObjects) Open(ctx context.Context, oid uint32, mode LargeObjectMod
 	if err != nil {
 		return nil, err
 	}
-	return &LargeObject{fd: fd, tx: o.tx, ctx: ctx}, nil
+	return &LargeObject{fd: fd, tx: o.tx, ctx: ctx, chunkSize: defaultLargeObjectChunkSize}, nil
 }
 
 // Unlink removes a large object from the database.
@@ -61,39 +65,78 @@ func (o *LargeObjects) Unlink(ctx context.Context, oid uint32) error {
 //    io.Seeker
 //    io.Closer
 type LargeObject struct {
-	ctx context.Context
-	tx  Tx
-	fd  int32
+	ctx       context.Context
+	tx        Tx
+	fd        int32
+	chunkSize int
 }
 
-// Write writes p to the large object and returns the number of bytes written and an error if not all of p was written.
-func (o *LargeObject) Write(p []byte) (int, error) {
-	var n int
-	err := o.tx.QueryRow(o.ctx, "select lowrite($1, $2)", o.fd, p).Scan(&n)
-	if err != nil {
-		return n, err
+// SetChunkSize sets the maximum number of bytes transferred to or from the server by a single lo_read or lo_write
+// call. Read and Write split larger buffers into multiple calls. Smaller chunks bound the memory used by the server
+// and the connection for each call at the cost of more round trips. The default is just under 1GB, the largest
+// value PostgreSQL can transfer in one call. It is used if size is not positive or is larger.
+func (o *LargeObject) SetChunkSize(size int) {
+	if size <= 0 || size > defaultLargeObjectChunkSize {
+		size = defaultLargeObjectChunkSize
 	}
+	o.chunkSize = size
+}
 
-	if n < 0 {
-		return 0, errors.New("failed to write to large object")
+// Write writes p to the large object and returns the number of bytes written and an error if not all of p was written.
+func (o *LargeObject) Write(p []byte) (int, error) {
+	total := 0
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > o.chunkSize {
+			chunk = chunk[:o.chunkSize]
+		}
+
+		var n int
+		err := o.tx.QueryRow(o.ctx, "select lowrite($1, $2)", o.fd, chunk).Scan(&n)
+		if err != nil {
+			return total, err
+		}
+
+		if n < 0 {
+			return total, errors.New("failed to write to large object")
+		}
+
+		total += n
+		p = p[n:]
+
+		if n < len(chunk) {
+			return total, io.ErrShortWrite
+		}
 	}
 
-	return n, nil
+	return total, nil
 }
 
 // Read reads up to len(p) bytes into p returning the number of bytes read.
 func (o *LargeObject) Read(p []byte) (int, error) {
-	var res []byte
-	err := o.tx.QueryRow(o.ctx, "select loread($1, $2)", o.fd, len(p)).Scan(&res)
-	copy(p, res)
-	if err != nil {
-		return len(res), err
-	}
-
-	if len(res) < len(p) {
-		err = io.EOF
+	total := 0
+	for {
+		chunkSize := len(p) - total
+		if chunkSize > o.chunkSize {
+			chunkSize = o.chunkSize
+		}
+
+		var res []byte
+		err := o.tx.QueryRow(o.ctx, "select loread($1, $2)", o.fd, chunkSize).Scan(&res)
+		copy(p[total:], res)
+		total += len(res)
+		if err != nil {
+			return total, err
+		}
+
+		if len(res) < chunkSize {
+			return total, io.EOF
+		}
+
+		if total == len(p) {
+			return total, nil
+		}
 	}
-	return len(res), err
 }
 
 // Seek moves the current location pointer to the new location specified by offset.
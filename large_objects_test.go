@@ -3,6 +3,7 @@ package pgx_test
 import (
 	"context"
 	"io"
+	"io/ioutil"
 	"os"
 	"testing"
 	"time"
@@ -300,3 +301,80 @@ func TestLargeObjectsMultipleTransactions(t *testing.T) {
 		t.Errorf("Expected undefined_object error (42704), got %#v", err)
 	}
 }
+
+func TestLargeObjectsChunkSize(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, err := pgx.Connect(ctx, os.Getenv("PGX_TEST_DATABASE"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	skipCockroachDB(t, conn, "Server does support large objects")
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback(ctx)
+
+	lo := tx.LargeObjects()
+
+	id, err := lo.Create(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	obj, err := lo.Open(ctx, id, pgx.LargeObjectModeRead|pgx.LargeObjectModeWrite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj.SetChunkSize(3)
+
+	data := []byte("abcdefghijklmnopqrstuvwxyz")
+	n, err := obj.Write(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(data) {
+		t.Errorf("Expected n to be %d, got %d", len(data), n)
+	}
+
+	_, err = obj.Seek(0, io.SeekStart)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 10)
+	n, err = obj.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf[:n]) != "abcdefghij" {
+		t.Errorf("Expected buf to be abcdefghij, got %q", buf[:n])
+	}
+
+	rest, err := ioutil.ReadAll(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(rest) != "klmnopqrstuvwxyz" {
+		t.Errorf("Expected rest to be klmnopqrstuvwxyz, got %q", rest)
+	}
+
+	err = obj.Truncate(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	size, err := obj.Seek(0, io.SeekEnd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != 5 {
+		t.Errorf("Expected size to be 5, got %d", size)
+	}
+}
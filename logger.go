@@ -5,6 +5,8 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"sync/atomic"
+	"time"
 )
 
 // The values for log levels are chosen such that the zero value means that no
@@ -75,6 +77,18 @@ func LogLevelFromString(s string) (LogLevel, error) {
 	}
 }
 
+// LogQueryArgsFunc returns the value logged as the arguments of a query. See ConnConfig.LogQueryArgs.
+type LogQueryArgsFunc func(sql string, args []interface{}) interface{}
+
+// LogQueryArgTypes is a LogQueryArgsFunc that logs the Go type of each argument instead of its value.
+func LogQueryArgTypes(sql string, args []interface{}) interface{} {
+	types := make([]string, len(args))
+	for i, a := range args {
+		types[i] = fmt.Sprintf("%T", a)
+	}
+	return types
+}
+
 func logQueryArgs(args []interface{}) []interface{} {
 	logArgs := make([]interface{}, 0, len(args))
 
@@ -96,3 +110,63 @@ func logQueryArgs(args []interface{}) []interface{} {
 
 	return logArgs
 }
+
+// SamplingLogger is a Logger that passes a sample of the messages it receives to another Logger. It can be used to log
+// a small fraction of queries with their arguments and timing in production. pgx logs a query with a single message
+// when it completes so a query is either logged in full or not at all. A SamplingLogger must not be copied after first
+// use.
+type SamplingLogger struct {
+	count uint64 // accessed atomically; first for alignment on 32-bit platforms
+
+	// Logger receives the sampled messages.
+	Logger Logger
+
+	// SampleRate is the fraction of messages passed to Logger from 0 to 1. Sampling is deterministic. e.g. With a rate
+	// of 0.01 every 100th message is logged.
+	SampleRate float64
+
+	// SlowThreshold, if greater than 0, causes messages with a "time" value of at least SlowThreshold to always be
+	// logged.
+	SlowThreshold time.Duration
+
+	// LogErrors causes messages at LogLevelWarn or LogLevelError and messages with an "err" value to always be logged.
+	LogErrors bool
+}
+
+func (l *SamplingLogger) Log(ctx context.Context, level LogLevel, msg string, data map[string]interface{}) {
+	if l.alwaysLog(level, data) || l.sample() {
+		l.Logger.Log(ctx, level, msg, data)
+	}
+}
+
+func (l *SamplingLogger) alwaysLog(level LogLevel, data map[string]interface{}) bool {
+	if l.LogErrors {
+		if level == LogLevelWarn || level == LogLevelError {
+			return true
+		}
+		if err, ok := data["err"]; ok && err != nil {
+			return true
+		}
+	}
+
+	if l.SlowThreshold > 0 {
+		if d, ok := data["time"].(time.Duration); ok && d >= l.SlowThreshold {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sample returns true when the count of sampled messages times SampleRate reaches the next whole number.
+func (l *SamplingLogger) sample() bool {
+	if l.SampleRate <= 0 {
+		return false
+	}
+	if l.SampleRate >= 1 {
+		return true
+	}
+
+	n := atomic.AddUint64(&l.count, 1)
+	return uint64(float64(n)*l.SampleRate) != uint64(float64(n-1)*l.SampleRate)
+}
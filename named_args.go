@@ -0,0 +1,230 @@
+package pgx
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// NamedArgs can be used as the only argument to a query method, after any query options, to use named placeholders
+// instead of ordinal ones. Each @name placeholder in the SQL is replaced with a $n placeholder and the arguments are
+// taken from the map by name. A name that is used more than once is bound to a single argument. A name that is not in
+// the map is bound to nil.
+//
+// A placeholder is an @ followed by a letter or underscore and then any number of letters, digits, or underscores.
+// Text in string literals, quoted identifiers, dollar-quoted strings, and comments is not rewritten. PostgreSQL
+// operators that contain an @, such as @> and <@, are not affected as long as they are not immediately followed by a
+// name.
+//
+//	conn.Query(ctx, "select * from widgets where name = @name and status = @status", pgx.NamedArgs{
+//		"name":   "foo",
+//		"status": "active",
+//	})
+//
+// NamedArgs is a distinct type rather than any map so map arguments, such as a json or hstore value, are not mistaken
+// for named arguments. For the same reason a struct argument is never bound by field name, as many argument types,
+// such as pgtype.Int4, are structs. Use NamedArgsFromStruct to build a NamedArgs from a struct. The :name syntax is not
+// supported because it is ambiguous with :: casts and array slices such as a[1:n].
+type NamedArgs map[string]interface{}
+
+// NamedArgsFromStruct returns a NamedArgs with the exported fields of the struct or pointer to struct s. A field is
+// named by its db tag or, without one, by its Go name. A field with the tag db:"-" is skipped. The fields of an
+// embedded struct are included as if they were fields of s unless the embedded struct has a db tag.
+func NamedArgsFromStruct(s interface{}) (NamedArgs, error) {
+	v := reflect.ValueOf(s)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, errors.New("NamedArgsFromStruct requires a non-nil struct pointer")
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("NamedArgsFromStruct requires a struct, got %T", s)
+	}
+
+	na := make(NamedArgs)
+	addStructNamedArgs(na, v)
+	return na, nil
+}
+
+func addStructNamedArgs(na NamedArgs, v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, hasTag := sf.Tag.Lookup("db")
+		if tag == "-" {
+			continue
+		}
+
+		if sf.Anonymous && !hasTag {
+			fv := v.Field(i)
+			if fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					continue
+				}
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct {
+				addStructNamedArgs(na, fv)
+				continue
+			}
+		}
+
+		if sf.PkgPath != "" {
+			continue // unexported
+		}
+
+		name := sf.Name
+		if hasTag {
+			name = tag
+		}
+		na[name] = v.Field(i).Interface()
+	}
+}
+
+// rewriteQuery returns sql with the named placeholders replaced by ordinal placeholders and the arguments in order.
+func (na NamedArgs) rewriteQuery(sql string) (string, []interface{}) {
+	var sb strings.Builder
+	var args []interface{}
+	ordinals := make(map[string]int)
+
+	for pos := 0; pos < len(sql); {
+		r, width := utf8.DecodeRuneInString(sql[pos:])
+
+		var end int
+		switch {
+		case r == '\'':
+			// A standard conforming string or, if preceded by E, a string with backslash escapes.
+			escapes := pos > 0 && (sql[pos-1] == 'E' || sql[pos-1] == 'e') && (pos == 1 || !isNamedArgRune(rune(sql[pos-2])))
+			end = skipQuoted(sql, pos, '\'', escapes)
+		case r == '"':
+			end = skipQuoted(sql, pos, '"', false)
+		case r == '$' && (pos == 0 || !isNamedArgRune(rune(sql[pos-1]))):
+			end = skipDollarQuoted(sql, pos)
+		case r == '-' && strings.HasPrefix(sql[pos:], "--"):
+			end = strings.IndexByte(sql[pos:], '\n')
+			if end == -1 {
+				end = len(sql)
+			} else {
+				end += pos + 1
+			}
+		case r == '/' && strings.HasPrefix(sql[pos:], "/*"):
+			end = skipBlockComment(sql, pos)
+		case r == '@':
+			nameEnd := pos + width
+			for nameEnd < len(sql) {
+				r, w := utf8.DecodeRuneInString(sql[nameEnd:])
+				if !isNamedArgRune(r) || (nameEnd == pos+width && unicode.IsDigit(r)) {
+					break
+				}
+				nameEnd += w
+			}
+			if nameEnd == pos+width {
+				end = nameEnd
+				break
+			}
+
+			name := sql[pos+width : nameEnd]
+			ordinal, ok := ordinals[name]
+			if !ok {
+				args = append(args, na[name])
+				ordinal = len(args)
+				ordinals[name] = ordinal
+			}
+			sb.WriteByte('$')
+			sb.WriteString(strconv.Itoa(ordinal))
+			pos = nameEnd
+			continue
+		default:
+			end = pos + width
+		}
+
+		sb.WriteString(sql[pos:end])
+		pos = end
+	}
+
+	return sb.String(), args
+}
+
+func isNamedArgRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// skipQuoted returns the position after the quoted text that starts at pos. A doubled quote is an escaped quote. If
+// backslashEscapes is true a backslash escapes the next character.
+func skipQuoted(sql string, pos int, quote byte, backslashEscapes bool) int {
+	for i := pos + 1; i < len(sql); i++ {
+		switch sql[i] {
+		case '\\':
+			if backslashEscapes {
+				i++
+			}
+		case quote:
+			if i+1 < len(sql) && sql[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(sql)
+}
+
+// skipDollarQuoted returns the position after the dollar-quoted string that starts at pos. If there is no dollar quote
+// at pos, such as with a $1 placeholder, it returns the position after the $.
+func skipDollarQuoted(sql string, pos int) int {
+	tagEnd := pos + 1
+	for tagEnd < len(sql) {
+		r, w := utf8.DecodeRuneInString(sql[tagEnd:])
+		if r == '$' {
+			break
+		}
+		if !isNamedArgRune(r) || (tagEnd == pos+1 && unicode.IsDigit(r)) {
+			return pos + 1
+		}
+		tagEnd += w
+	}
+	if tagEnd == len(sql) {
+		return pos + 1
+	}
+
+	tag := sql[pos : tagEnd+1]
+	closing := strings.Index(sql[tagEnd+1:], tag)
+	if closing == -1 {
+		return len(sql)
+	}
+	return tagEnd + 1 + closing + len(tag)
+}
+
+// skipBlockComment returns the position after the possibly nested block comment that starts at pos.
+func skipBlockComment(sql string, pos int) int {
+	depth := 0
+	for i := pos; i < len(sql)-1; i++ {
+		switch {
+		case sql[i] == '/' && sql[i+1] == '*':
+			depth++
+			i++
+		case sql[i] == '*' && sql[i+1] == '/':
+			depth--
+			i++
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(sql)
+}
+
+// rewriteNamedArgs rewrites sql and args if args is a single NamedArgs.
+func rewriteNamedArgs(sql string, args []interface{}) (string, []interface{}) {
+	if len(args) == 1 {
+		if na, ok := args[0].(NamedArgs); ok {
+			return na.rewriteQuery(sql)
+		}
+	}
+	return sql, args
+}
@@ -0,0 +1,160 @@
+package pgx_test
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamedArgs(t *testing.T) {
+	t.Parallel()
+
+	testWithAndWithoutPreferSimpleProtocol(t, func(t *testing.T, conn *pgx.Conn) {
+		var a, b, c int32
+		var literal, identifier, dollarQuoted string
+		err := conn.QueryRow(
+			context.Background(),
+			`select @a::int4 as "@a", @b::int4, @a::int4 + @b::int4, 'it''s @a', "@a", $$ @a $$ -- @c
+			from (select 'email@example.com' as "@a") t /* @c */`,
+			pgx.NamedArgs{"a": 1, "b": 2},
+		).Scan(&a, &b, &c, &literal, &identifier, &dollarQuoted)
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, a)
+		assert.EqualValues(t, 2, b)
+		assert.EqualValues(t, 3, c)
+		assert.Equal(t, "it's @a", literal)
+		assert.Equal(t, "email@example.com", identifier)
+		assert.Equal(t, " @a ", dollarQuoted)
+
+		var contains bool
+		err = conn.QueryRow(context.Background(), "select '{1,2,3}'::int4[] @> @elems::int4[]", pgx.NamedArgs{"elems": []int32{2, 3}}).Scan(&contains)
+		require.NoError(t, err)
+		assert.True(t, contains)
+
+		var isNull bool
+		err = conn.QueryRow(context.Background(), "select @missing::text is null", pgx.NamedArgs{}).Scan(&isNull)
+		require.NoError(t, err)
+		assert.True(t, isNull)
+
+		commandTag, err := conn.Exec(context.Background(), "select n from generate_series(1, @n::int4) n", pgx.NamedArgs{"n": 3})
+		require.NoError(t, err)
+		assert.EqualValues(t, 3, commandTag.RowsAffected())
+
+		ensureConnValid(t, conn)
+	})
+}
+
+func TestNamedArgsBatch(t *testing.T) {
+	t.Parallel()
+
+	testWithAndWithoutPreferSimpleProtocol(t, func(t *testing.T, conn *pgx.Conn) {
+		batch := &pgx.Batch{}
+		batch.Queue("select @s::text || @s::text", pgx.NamedArgs{"s": "foo"})
+
+		queued := batch.QueuedQueries()
+		require.Len(t, queued, 1)
+		assert.Equal(t, "select $1::text || $1::text", queued[0].SQL)
+		assert.Equal(t, []interface{}{"foo"}, queued[0].Arguments)
+
+		br := conn.SendBatch(context.Background(), batch)
+
+		var s string
+		err := br.QueryRow().Scan(&s)
+		require.NoError(t, err)
+		assert.Equal(t, "foofoo", s)
+
+		require.NoError(t, br.Close())
+
+		ensureConnValid(t, conn)
+	})
+}
+
+func TestNamedArgsMapArgumentIsNotRewritten(t *testing.T) {
+	t.Parallel()
+
+	testWithAndWithoutPreferSimpleProtocol(t, func(t *testing.T, conn *pgx.Conn) {
+		var s string
+		err := conn.QueryRow(context.Background(), "select $1::json->>'a'", map[string]interface{}{"a": "@b"}).Scan(&s)
+		require.NoError(t, err)
+		assert.Equal(t, "@b", s)
+
+		ensureConnValid(t, conn)
+	})
+}
+
+func TestNamedArgsCastsAndEscapeStrings(t *testing.T) {
+	t.Parallel()
+
+	testWithAndWithoutPreferSimpleProtocol(t, func(t *testing.T, conn *pgx.Conn) {
+		var n int32
+		var escaped string
+		err := conn.QueryRow(context.Background(), `select @x::text::int4, E'\\'@x'`, pgx.NamedArgs{"x": "7"}).Scan(&n, &escaped)
+		require.NoError(t, err)
+		assert.EqualValues(t, 7, n)
+		assert.Equal(t, "'@x", escaped)
+
+		ensureConnValid(t, conn)
+	})
+}
+
+type namedArgsBase struct {
+	ID int32
+}
+
+type namedArgsWidget struct {
+	namedArgsBase
+	Name   string `db:"name"`
+	Secret string `db:"-"`
+}
+
+func TestNamedArgsFromStruct(t *testing.T) {
+	t.Parallel()
+
+	na, err := pgx.NamedArgsFromStruct(&namedArgsWidget{namedArgsBase: namedArgsBase{ID: 1}, Name: "foo", Secret: "bar"})
+	require.NoError(t, err)
+	assert.Equal(t, pgx.NamedArgs{"ID": int32(1), "name": "foo"}, na)
+
+	_, err = pgx.NamedArgsFromStruct(42)
+	require.Error(t, err)
+
+	testWithAndWithoutPreferSimpleProtocol(t, func(t *testing.T, conn *pgx.Conn) {
+		var id int32
+		var name string
+		err := conn.QueryRow(context.Background(), "select @ID::int4, @name::text", na).Scan(&id, &name)
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, id)
+		assert.Equal(t, "foo", name)
+
+		ensureConnValid(t, conn)
+	})
+}
+
+func TestNamedArgsWithQueryInterceptor(t *testing.T) {
+	t.Parallel()
+
+	var interceptedSQL string
+	var interceptedArgs []interface{}
+	config := mustParseConfig(t, os.Getenv("PGX_TEST_DATABASE"))
+	config.QueryInterceptor = func(ctx context.Context, sql string, args []interface{}) (string, error) {
+		interceptedSQL = sql
+		interceptedArgs = args
+		return strings.Replace(sql, "/* tenant filter */", "where n <= 2", 1), nil
+	}
+
+	conn := mustConnect(t, config)
+	defer closeConn(t, conn)
+
+	var sum int64
+	err := conn.QueryRow(context.Background(), "select sum(n) + @add::int8 from generate_series(1, 10) n /* tenant filter */", pgx.NamedArgs{"add": 100}).Scan(&sum)
+	require.NoError(t, err)
+	assert.EqualValues(t, 103, sum)
+	assert.Equal(t, "select sum(n) + $1::int8 from generate_series(1, 10) n /* tenant filter */", interceptedSQL)
+	assert.Equal(t, []interface{}{100}, interceptedArgs)
+
+	ensureConnValid(t, conn)
+}
@@ -0,0 +1,247 @@
+// Package pgxlisten provides higher level tooling for PostgreSQL LISTEN / NOTIFY.
+/*
+A Listener holds a dedicated connection, issues LISTEN for every channel with a registered handler, and dispatches
+each notification to the handler for its channel. If the connection is lost the Listener reconnects and listens to
+all channels again. Notifications sent while the Listener is disconnected are lost. Use OnReconnect to resynchronize
+any state that depends on them.
+
+    listener := &pgxlisten.Listener{
+        Connect: func(ctx context.Context) (*pgx.Conn, error) {
+            return pgx.Connect(ctx, os.Getenv("DATABASE_URL"))
+        },
+    }
+
+    listener.Handle("jobs", pgxlisten.HandlerFunc(func(ctx context.Context, n *pgconn.Notification) error {
+        // do something with n.Payload
+        return nil
+    }))
+
+    err := listener.Listen(ctx) // runs until ctx is canceled
+
+There is no Close method. To shut a Listener down cancel the ctx passed to Listen. Listen then stops dispatching,
+closes its connection, and returns ctx.Err().
+*/
+package pgxlisten
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+var defaultReconnectDelay = time.Minute
+
+// Handler handles notifications received on a channel.
+type Handler interface {
+	// HandleNotification is called for each notification received on the channel the Handler was registered for. An
+	// error returned from HandleNotification is logged and does not stop the Listener.
+	HandleNotification(ctx context.Context, notification *pgconn.Notification) error
+}
+
+// HandlerFunc is an adapter to allow the use of an ordinary function as a Handler.
+type HandlerFunc func(ctx context.Context, notification *pgconn.Notification) error
+
+// HandleNotification calls f(ctx, notification).
+func (f HandlerFunc) HandleNotification(ctx context.Context, notification *pgconn.Notification) error {
+	return f(ctx, notification)
+}
+
+// Listener dispatches PostgreSQL notifications to handlers registered by channel name. A Listener must not be copied
+// after first use.
+type Listener struct {
+	// Connect establishes the connection used for listening. It is called on the first call to Listen and again each
+	// time the connection is lost. The Listener takes ownership of the returned connection and closes it when it is no
+	// longer needed. Required.
+	Connect func(ctx context.Context) (*pgx.Conn, error)
+
+	// Logger receives notifications that have no registered handler, handler errors, and connection errors. If nil
+	// nothing is logged.
+	Logger pgx.Logger
+
+	// LogLevel is the maximum level of messages passed to Logger. Default: pgx.LogLevelInfo.
+	LogLevel pgx.LogLevel
+
+	// ReconnectDelay is the duration to wait before reconnecting after the connection is lost. Default: 1 minute.
+	ReconnectDelay time.Duration
+
+	// MaxReconnectDelay enables exponential backoff when it is greater than ReconnectDelay. The delay starts at
+	// ReconnectDelay and doubles after each consecutive failed attempt up to MaxReconnectDelay. Default: 0 (constant
+	// delay).
+	MaxReconnectDelay time.Duration
+
+	// MaxReconnectAttempts is the number of consecutive failed attempts after which Listen gives up and returns the
+	// last error. An attempt fails if it cannot connect and listen to all channels. Default: 0 (unlimited).
+	MaxReconnectAttempts int
+
+	// MaxReconnectDuration is the time since the connection was lost after which Listen gives up and returns the last
+	// error, checked before each reconnect attempt. Default: 0 (unlimited).
+	MaxReconnectDuration time.Duration
+
+	// OnReconnect is called after a lost connection has been reestablished and all channels have been listened to
+	// again, but before any notifications are dispatched. It is not called for the first connection. Notifications
+	// sent while the Listener was disconnected are lost, so OnReconnect can be used to resynchronize state. If
+	// OnReconnect returns an error the connection is closed and treated as a failed attempt.
+	OnReconnect func(ctx context.Context, conn *pgx.Conn) error
+
+	handlers map[string]Handler
+}
+
+// Handle registers handler for notifications received on channel. Handle must be called before Listen and must not be
+// called concurrently with it.
+func (l *Listener) Handle(channel string, handler Handler) {
+	if l.handlers == nil {
+		l.handlers = make(map[string]Handler)
+	}
+
+	l.handlers[channel] = handler
+}
+
+// Listen listens to all channels with a registered handler and dispatches notifications until ctx is canceled. If the
+// connection is lost Listen waits ReconnectDelay, reconnects, and listens again. Listen always returns a non-nil error.
+// It returns ctx.Err() when ctx is canceled, including while waiting to reconnect, or the last connection error when
+// MaxReconnectAttempts or MaxReconnectDuration is exceeded. When Listen returns the connection is closed which
+// releases all LISTEN registrations on the server.
+func (l *Listener) Listen(ctx context.Context) error {
+	if l.Connect == nil {
+		return errors.New("Listen: Connect is nil")
+	}
+
+	reconnecting := false
+	everEstablished := false
+	failedAttempts := 0
+	var disconnectedAt time.Time
+
+	for {
+		established, err := l.listen(ctx, everEstablished)
+		if established {
+			everEstablished = true
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		l.log(ctx, pgx.LogLevelError, "listen failed", map[string]interface{}{"err": err})
+
+		if established || !reconnecting {
+			// The connection was lost after it was successfully established, or the first attempt failed.
+			reconnecting = true
+			failedAttempts = 0
+			disconnectedAt = time.Now()
+		}
+		if !established {
+			failedAttempts++
+		}
+
+		if l.MaxReconnectAttempts > 0 && failedAttempts >= l.MaxReconnectAttempts {
+			return fmt.Errorf("Listen: giving up after %d failed attempts: %w", failedAttempts, err)
+		}
+
+		delay := l.backoffDelay(failedAttempts)
+		if l.MaxReconnectDuration > 0 && time.Since(disconnectedAt)+delay > l.MaxReconnectDuration {
+			return fmt.Errorf("Listen: giving up after %v: %w", l.MaxReconnectDuration, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// listen connects, listens to all channels, and dispatches notifications until an error occurs. established reports
+// whether the connection was ready to receive notifications.
+func (l *Listener) listen(ctx context.Context, reconnecting bool) (established bool, err error) {
+	conn, err := l.Connect(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		closeCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+		conn.Close(closeCtx)
+	}()
+
+	for channel := range l.handlers {
+		_, err := conn.Exec(ctx, "listen "+pgx.Identifier{channel}.Sanitize())
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if reconnecting && l.OnReconnect != nil {
+		err = l.OnReconnect(ctx, conn)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return true, err
+		}
+
+		handler, ok := l.handlers[notification.Channel]
+		if !ok {
+			l.log(ctx, pgx.LogLevelWarn, "received notification for channel with no handler", map[string]interface{}{
+				"channel": notification.Channel,
+				"payload": notification.Payload,
+				"pid":     notification.PID,
+			})
+			continue
+		}
+
+		err = handler.HandleNotification(ctx, notification)
+		if err != nil {
+			l.log(ctx, pgx.LogLevelError, "handle notification failed", map[string]interface{}{
+				"channel": notification.Channel,
+				"err":     err,
+			})
+		}
+	}
+}
+
+func (l *Listener) reconnectDelay() time.Duration {
+	if l.ReconnectDelay == 0 {
+		return defaultReconnectDelay
+	}
+	return l.ReconnectDelay
+}
+
+// backoffDelay returns the delay before the next attempt after failedAttempts consecutive failures.
+func (l *Listener) backoffDelay(failedAttempts int) time.Duration {
+	delay := l.reconnectDelay()
+	if l.MaxReconnectDelay <= delay {
+		return delay
+	}
+
+	for i := 1; i < failedAttempts; i++ {
+		delay *= 2
+		if delay >= l.MaxReconnectDelay {
+			return l.MaxReconnectDelay
+		}
+	}
+
+	return delay
+}
+
+func (l *Listener) log(ctx context.Context, lvl pgx.LogLevel, msg string, data map[string]interface{}) {
+	if l.Logger == nil {
+		return
+	}
+
+	logLevel := l.LogLevel
+	if logLevel == 0 {
+		logLevel = pgx.LogLevelInfo
+	}
+	if logLevel < lvl {
+		return
+	}
+
+	l.Logger.Log(ctx, lvl, msg, data)
+}
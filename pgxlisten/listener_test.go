@@ -0,0 +1,345 @@
+package pgxlisten_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxlisten"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type logEntry struct {
+	lvl  pgx.LogLevel
+	msg  string
+	data map[string]interface{}
+}
+
+type testLogger struct {
+	mux  sync.Mutex
+	logs []logEntry
+}
+
+func (l *testLogger) Log(ctx context.Context, lvl pgx.LogLevel, msg string, data map[string]interface{}) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	l.logs = append(l.logs, logEntry{lvl: lvl, msg: msg, data: data})
+}
+
+func (l *testLogger) entries() []logEntry {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	return append([]logEntry(nil), l.logs...)
+}
+
+func TestListenerListenDispatchesNotifications(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	logger := &testLogger{}
+	listener := &pgxlisten.Listener{
+		Connect: func(ctx context.Context) (*pgx.Conn, error) {
+			return pgx.Connect(ctx, os.Getenv("PGX_TEST_DATABASE"))
+		},
+		Logger: logger,
+	}
+
+	notificationsChan := make(chan *pgconn.Notification)
+	listener.Handle("pgxlisten_a", pgxlisten.HandlerFunc(func(ctx context.Context, notification *pgconn.Notification) error {
+		select {
+		case notificationsChan <- notification:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}))
+
+	listenerCtx, listenerCancel := context.WithCancel(ctx)
+	listenErrChan := make(chan error, 1)
+	go func() {
+		listenErrChan <- listener.Listen(listenerCtx)
+	}()
+
+	notifier, err := pgx.Connect(ctx, os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+	defer notifier.Close(ctx)
+
+	// Keep notifying until the listener has issued LISTEN and received one.
+	var notification *pgconn.Notification
+	for notification == nil {
+		_, err = notifier.Exec(ctx, "select pg_notify('pgxlisten_a', 'hello')")
+		require.NoError(t, err)
+
+		select {
+		case notification = <-notificationsChan:
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	assert.Equal(t, "pgxlisten_a", notification.Channel)
+	assert.Equal(t, "hello", notification.Payload)
+
+	listenerCancel()
+	err = <-listenErrChan
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestListenerListenLogsUnhandledNotifications(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	logger := &testLogger{}
+	listener := &pgxlisten.Listener{
+		Connect: func(ctx context.Context) (*pgx.Conn, error) {
+			conn, err := pgx.Connect(ctx, os.Getenv("PGX_TEST_DATABASE"))
+			if err != nil {
+				return nil, err
+			}
+
+			// LISTEN issued outside of the Listener has no handler.
+			_, err = conn.Exec(ctx, "listen pgxlisten_unhandled")
+			if err != nil {
+				conn.Close(ctx)
+				return nil, err
+			}
+
+			return conn, nil
+		},
+		Logger: logger,
+	}
+
+	listenerCtx, listenerCancel := context.WithCancel(ctx)
+	listenErrChan := make(chan error, 1)
+	go func() {
+		listenErrChan <- listener.Listen(listenerCtx)
+	}()
+
+	notifier, err := pgx.Connect(ctx, os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+	defer notifier.Close(ctx)
+
+	var unhandled *logEntry
+	for unhandled == nil {
+		_, err = notifier.Exec(ctx, "select pg_notify('pgxlisten_unhandled', 'lost')")
+		require.NoError(t, err)
+		time.Sleep(100 * time.Millisecond)
+
+		for _, entry := range logger.entries() {
+			if entry.data["channel"] == "pgxlisten_unhandled" {
+				unhandled = &entry
+				break
+			}
+		}
+	}
+	assert.Equal(t, pgx.LogLevelWarn, unhandled.lvl)
+	assert.Equal(t, "lost", unhandled.data["payload"])
+
+	listenerCancel()
+	err = <-listenErrChan
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestListenerListenRequiresConnect(t *testing.T) {
+	t.Parallel()
+
+	listener := &pgxlisten.Listener{}
+	err := listener.Listen(context.Background())
+	require.Error(t, err)
+}
+
+func TestListenerListenReconnectsAndCallsOnReconnect(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	pidChan := make(chan uint32, 2)
+	reconnectChan := make(chan struct{}, 1)
+	listener := &pgxlisten.Listener{
+		Connect: func(ctx context.Context) (*pgx.Conn, error) {
+			conn, err := pgx.Connect(ctx, os.Getenv("PGX_TEST_DATABASE"))
+			if err != nil {
+				return nil, err
+			}
+			pidChan <- conn.PgConn().PID()
+			return conn, nil
+		},
+		ReconnectDelay: 10 * time.Millisecond,
+		OnReconnect: func(ctx context.Context, conn *pgx.Conn) error {
+			reconnectChan <- struct{}{}
+			return nil
+		},
+	}
+	listener.Handle("pgxlisten_reconnect", pgxlisten.HandlerFunc(func(ctx context.Context, notification *pgconn.Notification) error {
+		return nil
+	}))
+
+	listenerCtx, listenerCancel := context.WithCancel(ctx)
+	listenErrChan := make(chan error, 1)
+	go func() {
+		listenErrChan <- listener.Listen(listenerCtx)
+	}()
+
+	killer, err := pgx.Connect(ctx, os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+	defer killer.Close(ctx)
+
+	pid := <-pidChan
+	_, err = killer.Exec(ctx, "select pg_terminate_backend($1)", pid)
+	require.NoError(t, err)
+
+	select {
+	case <-reconnectChan:
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for reconnect")
+	}
+
+	listenerCancel()
+	err = <-listenErrChan
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestListenerListenDoesNotCallOnReconnectAfterFailedFirstConnect(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var mux sync.Mutex
+	attempts := 0
+	reconnects := 0
+	listener := &pgxlisten.Listener{
+		Connect: func(ctx context.Context) (*pgx.Conn, error) {
+			mux.Lock()
+			attempts++
+			first := attempts == 1
+			mux.Unlock()
+			if first {
+				return nil, errors.New("connect failed")
+			}
+			return pgx.Connect(ctx, os.Getenv("PGX_TEST_DATABASE"))
+		},
+		ReconnectDelay: 10 * time.Millisecond,
+		OnReconnect: func(ctx context.Context, conn *pgx.Conn) error {
+			mux.Lock()
+			reconnects++
+			mux.Unlock()
+			return nil
+		},
+	}
+
+	notifiedChan := make(chan struct{}, 1)
+	listener.Handle("pgxlisten_first_connect", pgxlisten.HandlerFunc(func(ctx context.Context, notification *pgconn.Notification) error {
+		select {
+		case notifiedChan <- struct{}{}:
+		default:
+		}
+		return nil
+	}))
+
+	listenerCtx, listenerCancel := context.WithCancel(ctx)
+	listenErrChan := make(chan error, 1)
+	go func() {
+		listenErrChan <- listener.Listen(listenerCtx)
+	}()
+
+	notifier, err := pgx.Connect(ctx, os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+	defer notifier.Close(ctx)
+
+	// Notifications sent before the Listener has listened are lost so keep sending until one is received.
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for received := false; !received; {
+		_, err = notifier.Exec(ctx, "select pg_notify('pgxlisten_first_connect', 'hi')")
+		require.NoError(t, err)
+
+		select {
+		case <-notifiedChan:
+			received = true
+		case <-ticker.C:
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for notification")
+		}
+	}
+
+	listenerCancel()
+	err = <-listenErrChan
+	assert.Equal(t, context.Canceled, err)
+
+	mux.Lock()
+	defer mux.Unlock()
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, 0, reconnects)
+}
+
+func TestListenerListenGivesUpAfterMaxReconnectAttempts(t *testing.T) {
+	t.Parallel()
+
+	connectErr := errors.New("connect failed")
+	attempts := 0
+	var attemptTimes []time.Time
+	listener := &pgxlisten.Listener{
+		Connect: func(ctx context.Context) (*pgx.Conn, error) {
+			attempts++
+			attemptTimes = append(attemptTimes, time.Now())
+			return nil, connectErr
+		},
+		ReconnectDelay:       10 * time.Millisecond,
+		MaxReconnectDelay:    40 * time.Millisecond,
+		MaxReconnectAttempts: 4,
+	}
+
+	err := listener.Listen(context.Background())
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, connectErr))
+	assert.Equal(t, 4, attempts)
+
+	// Delays of 10ms, 20ms, and 40ms.
+	assert.True(t, attemptTimes[3].Sub(attemptTimes[0]) >= 70*time.Millisecond)
+}
+
+func TestListenerListenGivesUpAfterMaxReconnectDuration(t *testing.T) {
+	t.Parallel()
+
+	connectErr := errors.New("connect failed")
+	listener := &pgxlisten.Listener{
+		Connect: func(ctx context.Context) (*pgx.Conn, error) {
+			return nil, connectErr
+		},
+		ReconnectDelay:       10 * time.Millisecond,
+		MaxReconnectDuration: 50 * time.Millisecond,
+	}
+
+	startTime := time.Now()
+	err := listener.Listen(context.Background())
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, connectErr))
+	assert.True(t, time.Since(startTime) < time.Second)
+}
+
+func TestListenerListenContextCanceledDuringReconnectDelay(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	listener := &pgxlisten.Listener{
+		Connect: func(ctx context.Context) (*pgx.Conn, error) {
+			return nil, errors.New("connect failed")
+		},
+		ReconnectDelay: time.Hour,
+	}
+
+	err := listener.Listen(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
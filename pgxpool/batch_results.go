@@ -1,6 +1,8 @@
 package pgxpool
 
 import (
+	"context"
+
 	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
 )
@@ -50,3 +52,16 @@ func (br *poolBatchResults) Close() error {
 	}
 	return err
 }
+
+// cancelBatchResults wraps a pgx.BatchResults and calls cancel when the batch is closed. It is used to release the
+// context created for Config.DefaultQueryTimeout.
+type cancelBatchResults struct {
+	pgx.BatchResults
+	cancel context.CancelFunc
+}
+
+func (br *cancelBatchResults) Close() error {
+	err := br.BatchResults.Close()
+	br.cancel()
+	return err
+}
@@ -26,6 +26,8 @@ func (c *Conn) Release() {
 	res := c.res
 	c.res = nil
 
+	c.p.emit(Event{Type: EventRelease})
+
 	now := time.Now()
 	if conn.IsClosed() || conn.PgConn().IsBusy() || conn.PgConn().TxStatus() != 'I' || (now.Sub(res.CreationTime()) > c.p.maxConnLifetime) {
 		res.Destroy()
@@ -47,23 +49,54 @@ func (c *Conn) Release() {
 }
 
 func (c *Conn) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
+	ctx, cancel := c.p.queryContext(ctx)
+	if cancel != nil {
+		defer cancel()
+	}
+
 	return c.Conn().Exec(ctx, sql, arguments...)
 }
 
 func (c *Conn) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
-	return c.Conn().Query(ctx, sql, args...)
+	ctx, cancel := c.p.queryContext(ctx)
+
+	rows, err := c.Conn().Query(ctx, sql, args...)
+	if cancel == nil {
+		return rows, err
+	}
+	if err != nil {
+		cancel()
+	}
+	return &cancelRows{Rows: rows, cancel: cancel}, err
 }
 
 func (c *Conn) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
-	return c.Conn().QueryRow(ctx, sql, args...)
+	ctx, cancel := c.p.queryContext(ctx)
+
+	row := c.Conn().QueryRow(ctx, sql, args...)
+	if cancel == nil {
+		return row
+	}
+	return &cancelRow{r: row, cancel: cancel}
 }
 
 func (c *Conn) QueryFunc(ctx context.Context, sql string, args []interface{}, scans []interface{}, f func(pgx.QueryFuncRow) error) (pgconn.CommandTag, error) {
+	ctx, cancel := c.p.queryContext(ctx)
+	if cancel != nil {
+		defer cancel()
+	}
+
 	return c.Conn().QueryFunc(ctx, sql, args, scans, f)
 }
 
 func (c *Conn) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
-	return c.Conn().SendBatch(ctx, b)
+	ctx, cancel := c.p.queryContext(ctx)
+
+	br := c.Conn().SendBatch(ctx, b)
+	if cancel == nil {
+		return br
+	}
+	return &cancelBatchResults{BatchResults: br, cancel: cancel}
 }
 
 func (c *Conn) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
@@ -71,11 +104,17 @@ func (c *Conn) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNam
 }
 
 func (c *Conn) Begin(ctx context.Context) (pgx.Tx, error) {
-	return c.Conn().Begin(ctx)
+	return c.BeginTx(ctx, pgx.TxOptions{})
 }
 
 func (c *Conn) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
-	return c.Conn().BeginTx(ctx, txOptions)
+	t, err := c.Conn().BeginTx(ctx, txOptions)
+	if err != nil || c.p.defaultQueryTimeout <= 0 {
+		return t, err
+	}
+
+	// c is not set as the caller owns the acquired Conn and releases it.
+	return &Tx{t: t, p: c.p}, nil
 }
 
 func (c *Conn) BeginFunc(ctx context.Context, f func(pgx.Tx) error) error {
@@ -90,6 +129,11 @@ func (c *Conn) Ping(ctx context.Context) error {
 	return c.Conn().Ping(ctx)
 }
 
+// PID returns the process ID of the PostgreSQL backend serving the acquired connection.
+func (c *Conn) PID() uint32 {
+	return c.Conn().PID()
+}
+
 func (c *Conn) Conn() *pgx.Conn {
 	return c.connResource().conn
 }
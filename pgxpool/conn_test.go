@@ -78,3 +78,20 @@ func TestConnCopyFrom(t *testing.T) {
 
 	testCopyFrom(t, c)
 }
+
+func TestConnPID(t *testing.T) {
+	t.Parallel()
+
+	pool, err := pgxpool.Connect(context.Background(), os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+	defer pool.Close()
+
+	c, err := pool.Acquire(context.Background())
+	require.NoError(t, err)
+	defer c.Release()
+
+	var pid uint32
+	err = c.QueryRow(context.Background(), "select pg_backend_pid()").Scan(&pid)
+	require.NoError(t, err)
+	require.Equal(t, pid, c.PID())
+}
@@ -0,0 +1,58 @@
+package pgxpool
+
+import (
+	"time"
+)
+
+// EventType identifies the kind of an Event.
+type EventType int
+
+// Pool event types
+const (
+	// EventAcquireStart occurs when Acquire is called, before the pool is checked for an available connection.
+	EventAcquireStart EventType = iota + 1
+
+	// EventAcquireEnd occurs when Acquire returns. Event.Duration is the time spent waiting for a connection and
+	// Event.Err is the error returned by Acquire, if any.
+	EventAcquireEnd
+
+	// EventRelease occurs when an acquired connection is released.
+	EventRelease
+
+	// EventConnect occurs when the pool has finished establishing a new connection. Event.Duration is the time spent
+	// connecting, including AfterConnect. Event.Err is set if the connection could not be established.
+	EventConnect
+
+	// EventConnDestroyed occurs when a connection is removed from the pool and closed.
+	EventConnDestroyed
+)
+
+func (et EventType) String() string {
+	switch et {
+	case EventAcquireStart:
+		return "acquire start"
+	case EventAcquireEnd:
+		return "acquire end"
+	case EventRelease:
+		return "release"
+	case EventConnect:
+		return "connect"
+	case EventConnDestroyed:
+		return "conn destroyed"
+	default:
+		return "unknown"
+	}
+}
+
+// Event describes something that happened in a Pool. It is passed to Config.OnEvent.
+type Event struct {
+	Type     EventType
+	Duration time.Duration
+	Err      error
+}
+
+func (p *Pool) emit(e Event) {
+	if p.onEvent != nil {
+		p.onEvent(e)
+	}
+}
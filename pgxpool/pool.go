@@ -2,6 +2,7 @@ package pgxpool
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"runtime"
 	"strconv"
@@ -75,13 +76,20 @@ type Pool struct {
 	afterConnect      func(context.Context, *pgx.Conn) error
 	beforeAcquire     func(context.Context, *pgx.Conn) bool
 	afterRelease      func(*pgx.Conn) bool
+	onEvent           func(Event)
 	minConns          int32
 	maxConnLifetime   time.Duration
 	maxConnIdleTime   time.Duration
 	healthCheckPeriod time.Duration
 
+	defaultQueryTimeout time.Duration
+	preparedStatements  map[string]string
+
 	closeOnce sync.Once
 	closeChan chan struct{}
+
+	connsMux sync.Mutex
+	conns    map[*pgx.Conn]struct{} // every established connection, used by Drain to find connections still in use
 }
 
 // Config is the configuration struct for creating a pool. It must be created by ParseConfig and then it can be
@@ -96,6 +104,16 @@ type Config struct {
 	// AfterConnect is called after a connection is established, but before it is added to the pool.
 	AfterConnect func(context.Context, *pgx.Conn) error
 
+	// PreparedStatements maps statement names to SQL. Each statement is prepared with Conn.Prepare on every new
+	// connection after AfterConnect so the first use of a statement on a connection does not need a round trip to
+	// prepare it. The statements are executed by passing their name as the sql argument of Exec, Query, etc. If any
+	// statement fails to prepare the connection is closed and the error is returned by the operation that created it.
+	//
+	// These named statements are separate from the automatic statement cache, which is used for SQL that is not the
+	// name of a prepared statement. Executing DEALLOCATE ALL on a connection removes them from the server without
+	// pgx knowing, after which using them fails on that connection. Use Conn.Deallocate instead.
+	PreparedStatements map[string]string
+
 	// BeforeAcquire is called before before a connection is acquired from the pool. It must return true to allow the
 	// acquision or false to indicate that the connection should be destroyed and a different connection should be
 	// acquired.
@@ -105,6 +123,12 @@ type Config struct {
 	// return the connection to the pool or false to destroy the connection.
 	AfterRelease func(*pgx.Conn) bool
 
+	// OnEvent is called when a connection is acquired, released, established, or destroyed. It is intended for feeding
+	// metrics collectors without polling Stat. See EventType for the events and the Event fields they set. OnEvent is
+	// called synchronously from the goroutine performing the operation, so a slow OnEvent will stall the pool. If the
+	// work to be done is not trivial, send the Event to a buffered channel and process it elsewhere.
+	OnEvent func(Event)
+
 	// MaxConnLifetime is the duration since creation after which a connection will be automatically closed.
 	MaxConnLifetime time.Duration
 
@@ -121,6 +145,15 @@ type Config struct {
 	// HealthCheckPeriod is the duration between checks of the health of idle connections.
 	HealthCheckPeriod time.Duration
 
+	// DefaultQueryTimeout is the maximum duration of a single statement executed through the pool. When non-zero it is
+	// applied as a context deadline to Exec, Query, QueryRow, QueryFunc, and SendBatch on the Pool, on a Conn acquired
+	// from the Pool, on a Tx started with Begin or BeginTx on the Pool or on an acquired Conn, and on a pseudo nested
+	// transaction started with Begin on such a Tx. It is not applied to the pgx.Tx passed to the function of BeginFunc or
+	// BeginTxFunc. It is applied per statement, so it does not limit the total duration of a transaction. If the context
+	// passed by the caller already has an earlier deadline that deadline is used instead. As with any context deadline, a
+	// statement interrupted by DefaultQueryTimeout causes the underlying connection to be closed.
+	DefaultQueryTimeout time.Duration
+
 	// If set to true, pool doesn't do any I/O operation on initialization.
 	// And connects to the server only when the pool starts to be used.
 	// The default is false.
@@ -136,11 +169,24 @@ func (c *Config) Copy() *Config {
 	newConfig := new(Config)
 	*newConfig = *c
 	newConfig.ConnConfig = c.ConnConfig.Copy()
+	newConfig.PreparedStatements = copyPreparedStatements(c.PreparedStatements)
 	return newConfig
 }
 
 func (c *Config) ConnString() string { return c.ConnConfig.ConnString() }
 
+func copyPreparedStatements(preparedStatements map[string]string) map[string]string {
+	if preparedStatements == nil {
+		return nil
+	}
+
+	statementsCopy := make(map[string]string, len(preparedStatements))
+	for name, sql := range preparedStatements {
+		statementsCopy[name] = sql
+	}
+	return statementsCopy
+}
+
 // Connect creates a new Pool and immediately establishes one connection. ctx can be used to cancel this initial
 // connection. See ParseConfig for information on connString format.
 func Connect(ctx context.Context, connString string) (*Pool, error) {
@@ -167,15 +213,27 @@ func ConnectConfig(ctx context.Context, config *Config) (*Pool, error) {
 		afterConnect:      config.AfterConnect,
 		beforeAcquire:     config.BeforeAcquire,
 		afterRelease:      config.AfterRelease,
+		onEvent:           config.OnEvent,
 		minConns:          config.MinConns,
 		maxConnLifetime:   config.MaxConnLifetime,
 		maxConnIdleTime:   config.MaxConnIdleTime,
 		healthCheckPeriod: config.HealthCheckPeriod,
 		closeChan:         make(chan struct{}),
+		conns:             make(map[*pgx.Conn]struct{}),
+
+		defaultQueryTimeout: config.DefaultQueryTimeout,
+		preparedStatements:  copyPreparedStatements(config.PreparedStatements),
 	}
 
 	p.p = puddle.NewPool(
-		func(ctx context.Context) (interface{}, error) {
+		func(ctx context.Context) (value interface{}, err error) {
+			if p.onEvent != nil {
+				startTime := time.Now()
+				defer func() {
+					p.emit(Event{Type: EventConnect, Duration: time.Since(startTime), Err: err})
+				}()
+			}
+
 			connConfig := p.config.ConnConfig
 
 			if p.beforeConnect != nil {
@@ -198,6 +256,18 @@ func ConnectConfig(ctx context.Context, config *Config) (*Pool, error) {
 				}
 			}
 
+			for name, sql := range p.preparedStatements {
+				_, err = conn.Prepare(ctx, name, sql)
+				if err != nil {
+					conn.Close(ctx)
+					return nil, fmt.Errorf("failed to prepare statement %s: %w", name, err)
+				}
+			}
+
+			p.connsMux.Lock()
+			p.conns[conn] = struct{}{}
+			p.connsMux.Unlock()
+
 			cr := &connResource{
 				conn:      conn,
 				conns:     make([]Conn, 64),
@@ -210,12 +280,16 @@ func ConnectConfig(ctx context.Context, config *Config) (*Pool, error) {
 		func(value interface{}) {
 			ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 			conn := value.(*connResource).conn
+			p.connsMux.Lock()
+			delete(p.conns, conn)
+			p.connsMux.Unlock()
 			conn.Close(ctx)
 			select {
 			case <-conn.PgConn().CleanupDone():
 			case <-ctx.Done():
 			}
 			cancel()
+			p.emit(Event{Type: EventConnDestroyed})
 		},
 		config.MaxConns,
 	)
@@ -335,6 +409,48 @@ func (p *Pool) Close() {
 	})
 }
 
+// Drain gracefully closes the pool. Like Close, it rejects future Acquire calls with puddle.ErrClosedPool, closes idle
+// connections, and waits for acquired connections to be released and closed. If that finishes before ctx is done Drain
+// returns 0.
+//
+// If ctx is done first, Drain closes the network connection of each connection that is still acquired so any query in
+// progress on it fails, and returns without waiting further. The returned count is the number of network connections
+// it closed. A connection whose network connection was already closed is not counted. The force closed connections
+// are still acquired: each must be released as usual and is destroyed then. Closing the pool continues in the
+// background until every acquired connection has been released, so a connection that is never released keeps that
+// background close running. Call Close to wait for it to finish.
+func (p *Pool) Drain(ctx context.Context) int {
+	closed := make(chan struct{})
+	go func() {
+		p.Close()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+		return 0
+	case <-ctx.Done():
+	}
+
+	p.connsMux.Lock()
+	conns := make([]*pgx.Conn, 0, len(p.conns))
+	for conn := range p.conns {
+		conns = append(conns, conn)
+	}
+	p.connsMux.Unlock()
+
+	// The net.Conn is safe to close while another goroutine is using the connection. Closing it fails if the connection
+	// was already closed normally.
+	forceClosed := 0
+	for _, conn := range conns {
+		if conn.PgConn().Conn().Close() == nil {
+			forceClosed++
+		}
+	}
+
+	return forceClosed
+}
+
 func (p *Pool) backgroundHealthCheck() {
 	ticker := time.NewTicker(p.healthCheckPeriod)
 
@@ -375,7 +491,62 @@ func (p *Pool) checkMinConns() {
 	}
 }
 
-func (p *Pool) Acquire(ctx context.Context) (*Conn, error) {
+// Warmup establishes connections until the pool has MinConns connections. Unlike the background health check it
+// waits for the connections to be established, so it can be used during startup to fail fast if the database is
+// unreachable and to pay the connection cost before serving traffic. It does nothing if the pool already has at least
+// MinConns connections.
+//
+// Warmup works by acquiring MinConns connections at once, up to MaxConns, and then releasing them. Connections are
+// established through the same accounting as Acquire so concurrent Acquire and Warmup calls never grow the pool beyond
+// MaxConns. While Warmup holds the connections other Acquire calls may have to wait, and when other goroutines hold
+// connections of a pool that is near MaxConns, Warmup waits for them to be released. ctx limits the time spent
+// connecting and waiting. The first error encountered is returned.
+func (p *Pool) Warmup(ctx context.Context) error {
+	target := p.minConns
+	if target > p.config.MaxConns {
+		target = p.config.MaxConns
+	}
+
+	if p.Stat().TotalConns() >= target {
+		return nil
+	}
+
+	type acquireResult struct {
+		res *puddle.Resource
+		err error
+	}
+	resultChan := make(chan acquireResult, target)
+	for i := int32(0); i < target; i++ {
+		go func() {
+			res, err := p.p.Acquire(ctx)
+			resultChan <- acquireResult{res: res, err: err}
+		}()
+	}
+
+	var firstErr error
+	for i := int32(0); i < target; i++ {
+		result := <-resultChan
+		if result.err != nil {
+			if firstErr == nil {
+				firstErr = result.err
+			}
+			continue
+		}
+		defer result.res.Release()
+	}
+
+	return firstErr
+}
+
+func (p *Pool) Acquire(ctx context.Context) (c *Conn, err error) {
+	if p.onEvent != nil {
+		p.emit(Event{Type: EventAcquireStart})
+		startTime := time.Now()
+		defer func() {
+			p.emit(Event{Type: EventAcquireEnd, Duration: time.Since(startTime), Err: err})
+		}()
+	}
+
 	for {
 		res, err := p.p.Acquire(ctx)
 		if err != nil {
@@ -391,6 +562,47 @@ func (p *Pool) Acquire(ctx context.Context) (*Conn, error) {
 	}
 }
 
+// ErrNoIdleConns is returned by TryAcquire when the pool has no idle connections.
+var ErrNoIdleConns = errors.New("no idle connections available")
+
+// TryAcquire returns an idle *Conn from the pool without waiting. If no connection is idle it returns ErrNoIdleConns
+// immediately instead of establishing a new connection or waiting for one to be released. This allows latency
+// sensitive code to fall back to another strategy rather than queue behind other callers. ctx is only passed to
+// BeforeAcquire.
+//
+// puddle has no non-blocking acquire, so TryAcquire briefly takes all idle connections and returns all but one to the
+// pool before calling BeforeAcquire. Its cost is proportional to the number of idle connections, and a concurrent
+// Acquire that runs in that window does not see the idle connections and may establish a new connection or wait.
+func (p *Pool) TryAcquire(ctx context.Context) (c *Conn, err error) {
+	if p.onEvent != nil {
+		p.emit(Event{Type: EventAcquireStart})
+		startTime := time.Now()
+		defer func() {
+			p.emit(Event{Type: EventAcquireEnd, Duration: time.Since(startTime), Err: err})
+		}()
+	}
+
+	for {
+		resources := p.p.AcquireAllIdle()
+		if len(resources) == 0 {
+			return nil, ErrNoIdleConns
+		}
+
+		// The most recently released resource is last, which matches the one Acquire would have chosen.
+		res := resources[len(resources)-1]
+		for _, r := range resources[:len(resources)-1] {
+			r.ReleaseUnused()
+		}
+
+		cr := res.Value().(*connResource)
+		if p.beforeAcquire == nil || p.beforeAcquire(ctx, cr.conn) {
+			return cr.getConn(p, res), nil
+		}
+
+		res.Destroy()
+	}
+}
+
 // AcquireFunc acquires a *Conn and calls f with that *Conn. ctx will only affect the Acquire. It has no effect on the
 // call of f. The return value is either an error acquiring the *Conn or the return value of f. The *Conn is
 // automatically released after the call of f.
@@ -483,6 +695,21 @@ func (p *Pool) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
 	return &poolBatchResults{br: br, c: c}
 }
 
+// queryContext returns a context for executing a single statement. If the pool has a DefaultQueryTimeout it is applied
+// unless ctx already has an earlier deadline. If no timeout is applied ctx is returned with a nil cancel function so
+// the default path does not allocate. Otherwise the returned cancel function must be called.
+func (p *Pool) queryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if p.defaultQueryTimeout <= 0 {
+		return ctx, nil
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= p.defaultQueryTimeout {
+		return ctx, nil
+	}
+
+	return context.WithTimeout(ctx, p.defaultQueryTimeout)
+}
+
 func (p *Pool) Begin(ctx context.Context) (pgx.Tx, error) {
 	return p.BeginTx(ctx, pgx.TxOptions{})
 }
@@ -492,13 +719,13 @@ func (p *Pool) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, er
 		return nil, err
 	}
 
-	t, err := c.BeginTx(ctx, txOptions)
+	t, err := c.Conn().BeginTx(ctx, txOptions)
 	if err != nil {
 		c.Release()
 		return nil, err
 	}
 
-	return &Tx{t: t, c: c}, err
+	return &Tx{t: t, c: c, p: p}, err
 }
 
 func (p *Pool) BeginFunc(ctx context.Context, f func(pgx.Tx) error) error {
@@ -5,11 +5,15 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jackc/puddle"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -184,6 +188,63 @@ func TestPoolAfterConnect(t *testing.T) {
 	assert.EqualValues(t, 1, n)
 }
 
+func TestPoolPreparedStatements(t *testing.T) {
+	t.Parallel()
+
+	config, err := pgxpool.ParseConfig(os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+
+	config.AfterConnect = func(ctx context.Context, c *pgx.Conn) error {
+		_, err := c.Exec(ctx, "create temporary table foo(n int4)")
+		return err
+	}
+	config.PreparedStatements = map[string]string{
+		"insertFoo": "insert into foo(n) values ($1)",
+		"sumFoo":    "select coalesce(sum(n), 0) from foo",
+	}
+	config.MaxConns = 2
+
+	db, err := pgxpool.ConnectConfig(context.Background(), config)
+	require.NoError(t, err)
+	defer db.Close()
+
+	// Changing the config after the pool is created does not affect it.
+	config.PreparedStatements["sumFoo"] = "select 42"
+
+	c1, err := db.Acquire(context.Background())
+	require.NoError(t, err)
+	defer c1.Release()
+	c2, err := db.Acquire(context.Background())
+	require.NoError(t, err)
+	defer c2.Release()
+
+	for _, c := range []*pgxpool.Conn{c1, c2} {
+		_, err = c.Exec(context.Background(), "insertFoo", 7)
+		require.NoError(t, err)
+
+		var n int64
+		err = c.QueryRow(context.Background(), "sumFoo").Scan(&n)
+		require.NoError(t, err)
+		assert.EqualValues(t, 7, n)
+	}
+}
+
+func TestPoolPreparedStatementsFailure(t *testing.T) {
+	t.Parallel()
+
+	config, err := pgxpool.ParseConfig(os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+
+	config.PreparedStatements = map[string]string{
+		"bad": "select * from table_that_does_not_exist",
+	}
+
+	db, err := pgxpool.ConnectConfig(context.Background(), config)
+	require.Error(t, err)
+	require.Nil(t, db)
+	require.Contains(t, err.Error(), "failed to prepare statement bad")
+}
+
 func TestPoolBeforeAcquire(t *testing.T) {
 	t.Parallel()
 
@@ -269,6 +330,161 @@ func TestPoolAfterRelease(t *testing.T) {
 	assert.EqualValues(t, 5, len(connPIDs))
 }
 
+func TestPoolOnEvent(t *testing.T) {
+	t.Parallel()
+
+	config, err := pgxpool.ParseConfig(os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+
+	var mux sync.Mutex
+	var events []pgxpool.Event
+	config.OnEvent = func(e pgxpool.Event) {
+		mux.Lock()
+		events = append(events, e)
+		mux.Unlock()
+	}
+	config.LazyConnect = true
+
+	pool, err := pgxpool.ConnectConfig(context.Background(), config)
+	require.NoError(t, err)
+
+	c, err := pool.Acquire(context.Background())
+	require.NoError(t, err)
+	c.Release()
+	waitForReleaseToComplete()
+
+	pool.Close()
+
+	mux.Lock()
+	defer mux.Unlock()
+
+	var eventTypes []pgxpool.EventType
+	for _, e := range events {
+		eventTypes = append(eventTypes, e.Type)
+		assert.NoError(t, e.Err)
+	}
+	assert.Equal(t, []pgxpool.EventType{
+		pgxpool.EventAcquireStart,
+		pgxpool.EventConnect,
+		pgxpool.EventAcquireEnd,
+		pgxpool.EventRelease,
+		pgxpool.EventConnDestroyed,
+	}, eventTypes)
+
+	assert.True(t, events[1].Duration > 0)
+	assert.True(t, events[2].Duration >= events[1].Duration)
+}
+
+func TestPoolTryAcquire(t *testing.T) {
+	t.Parallel()
+
+	config, err := pgxpool.ParseConfig(os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+	config.MaxConns = 2
+	config.LazyConnect = true
+
+	db, err := pgxpool.ConnectConfig(context.Background(), config)
+	require.NoError(t, err)
+	defer db.Close()
+
+	// No connections have been established yet.
+	_, err = db.TryAcquire(context.Background())
+	require.Equal(t, pgxpool.ErrNoIdleConns, err)
+	assert.EqualValues(t, 0, db.Stat().TotalConns())
+
+	c1, err := db.Acquire(context.Background())
+	require.NoError(t, err)
+	c2, err := db.Acquire(context.Background())
+	require.NoError(t, err)
+
+	// All connections are in use.
+	_, err = db.TryAcquire(context.Background())
+	require.Equal(t, pgxpool.ErrNoIdleConns, err)
+
+	c1.Release()
+	c2.Release()
+	waitForReleaseToComplete()
+
+	c, err := db.TryAcquire(context.Background())
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, db.Stat().AcquiredConns())
+	assert.EqualValues(t, 1, db.Stat().IdleConns())
+
+	var n int32
+	err = c.QueryRow(context.Background(), "select 1").Scan(&n)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, n)
+	c.Release()
+	waitForReleaseToComplete()
+
+	assert.EqualValues(t, 2, db.Stat().IdleConns())
+}
+
+func TestPoolTryAcquireBeforeAcquire(t *testing.T) {
+	t.Parallel()
+
+	config, err := pgxpool.ParseConfig(os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+
+	config.BeforeAcquire = func(ctx context.Context, c *pgx.Conn) bool {
+		return false
+	}
+
+	db, err := pgxpool.ConnectConfig(context.Background(), config)
+	require.NoError(t, err)
+	defer db.Close()
+
+	// The idle connection is rejected and destroyed.
+	_, err = db.TryAcquire(context.Background())
+	require.Equal(t, pgxpool.ErrNoIdleConns, err)
+	assert.EqualValues(t, 0, db.Stat().TotalConns())
+}
+
+func TestPoolTryAcquireDoesNotHoldOtherIdleConns(t *testing.T) {
+	t.Parallel()
+
+	config, err := pgxpool.ParseConfig(os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+	config.MinConns = 3
+	config.MaxConns = 10
+	config.LazyConnect = true
+
+	var db *pgxpool.Pool
+	var inTryAcquire int32
+	acquireErrChan := make(chan error, 1)
+	config.BeforeAcquire = func(ctx context.Context, c *pgx.Conn) bool {
+		if !atomic.CompareAndSwapInt32(&inTryAcquire, 1, 0) {
+			return true
+		}
+
+		// An Acquire on another goroutine while TryAcquire runs BeforeAcquire finds the other idle connections.
+		go func() {
+			c, err := db.Acquire(ctx)
+			if err == nil {
+				c.Release()
+			}
+			acquireErrChan <- err
+		}()
+		require.NoError(t, <-acquireErrChan)
+		return true
+	}
+
+	db, err = pgxpool.ConnectConfig(context.Background(), config)
+	require.NoError(t, err)
+	defer db.Close()
+
+	require.NoError(t, db.Warmup(context.Background()))
+	require.EqualValues(t, 3, db.Stat().TotalConns())
+
+	atomic.StoreInt32(&inTryAcquire, 1)
+	c, err := db.TryAcquire(context.Background())
+	require.NoError(t, err)
+	c.Release()
+	waitForReleaseToComplete()
+
+	assert.EqualValues(t, 3, db.Stat().TotalConns())
+}
+
 func TestPoolAcquireAllIdle(t *testing.T) {
 	t.Parallel()
 
@@ -349,6 +565,66 @@ func TestConnReleaseClosesBusyConn(t *testing.T) {
 	assert.EqualValues(t, 0, stats.TotalConns())
 }
 
+func TestPoolDrain(t *testing.T) {
+	t.Parallel()
+
+	db, err := pgxpool.Connect(context.Background(), os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	c, err := db.Acquire(context.Background())
+	require.NoError(t, err)
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		c.Release()
+	}()
+
+	forceClosed := db.Drain(context.Background())
+	assert.Equal(t, 0, forceClosed)
+	assert.EqualValues(t, 0, db.Stat().TotalConns())
+
+	_, err = db.Acquire(context.Background())
+	require.True(t, errors.Is(err, puddle.ErrClosedPool))
+}
+
+func TestPoolDrainForceClosesConnsAfterContextDone(t *testing.T) {
+	t.Parallel()
+
+	db, err := pgxpool.Connect(context.Background(), os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	c, err := db.Acquire(context.Background())
+	require.NoError(t, err)
+
+	queryErrChan := make(chan error)
+	go func() {
+		_, err := c.Exec(context.Background(), "select pg_sleep(10)")
+		c.Release()
+		queryErrChan <- err
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	forceClosed := db.Drain(ctx)
+	assert.Equal(t, 1, forceClosed)
+
+	select {
+	case err := <-queryErrChan:
+		require.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("query was not interrupted")
+	}
+
+	_, err = db.Acquire(context.Background())
+	require.True(t, errors.Is(err, puddle.ErrClosedPool))
+
+	// The force closed connection has been released so the close that Drain started finishes.
+	db.Close()
+	assert.EqualValues(t, 0, db.Stat().TotalConns())
+}
+
 func TestPoolBackgroundChecksMaxConnLifetime(t *testing.T) {
 	t.Parallel()
 
@@ -411,6 +687,116 @@ func TestPoolBackgroundChecksMinConns(t *testing.T) {
 	assert.EqualValues(t, 2, stats.TotalConns())
 }
 
+func TestPoolWarmup(t *testing.T) {
+	t.Parallel()
+
+	config, err := pgxpool.ParseConfig(os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+	config.MinConns = 3
+	config.MaxConns = 5
+	config.LazyConnect = true
+
+	pool, err := pgxpool.ConnectConfig(context.Background(), config)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	assert.EqualValues(t, 0, pool.Stat().TotalConns())
+
+	err = pool.Warmup(context.Background())
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, pool.Stat().TotalConns())
+	assert.EqualValues(t, 3, pool.Stat().IdleConns())
+
+	// Already warm
+	err = pool.Warmup(context.Background())
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, pool.Stat().TotalConns())
+}
+
+func TestPoolWarmupDoesNotExceedMaxConns(t *testing.T) {
+	t.Parallel()
+
+	config, err := pgxpool.ParseConfig(os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+	config.MinConns = 5
+	config.MaxConns = 2
+	config.LazyConnect = true
+
+	pool, err := pgxpool.ConnectConfig(context.Background(), config)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	err = pool.Warmup(context.Background())
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, pool.Stat().TotalConns())
+}
+
+func TestPoolWarmupConcurrentWithAcquire(t *testing.T) {
+	t.Parallel()
+
+	config, err := pgxpool.ParseConfig(os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+	config.MinConns = 3
+	config.MaxConns = 4
+	config.LazyConnect = true
+
+	pool, err := pgxpool.ConnectConfig(context.Background(), config)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	var wg sync.WaitGroup
+	errChan := make(chan error, 6)
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errChan <- pool.Warmup(context.Background())
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c, err := pool.Acquire(context.Background())
+			if err == nil {
+				assert.LessOrEqual(t, pool.Stat().TotalConns(), config.MaxConns)
+				time.Sleep(10 * time.Millisecond)
+				c.Release()
+			}
+			errChan <- err
+		}()
+	}
+	wg.Wait()
+	close(errChan)
+
+	for err := range errChan {
+		require.NoError(t, err)
+	}
+	assert.LessOrEqual(t, pool.Stat().TotalConns(), config.MaxConns)
+	assert.GreaterOrEqual(t, pool.Stat().TotalConns(), config.MinConns)
+}
+
+func TestPoolWarmupReturnsConnectError(t *testing.T) {
+	t.Parallel()
+
+	config, err := pgxpool.ParseConfig(os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+	config.MinConns = 2
+	config.LazyConnect = true
+	connectErr := errors.New("unreachable")
+	config.BeforeConnect = func(context.Context, *pgx.ConnConfig) error {
+		return connectErr
+	}
+
+	pool, err := pgxpool.ConnectConfig(context.Background(), config)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	err = pool.Warmup(context.Background())
+	assert.Equal(t, connectErr, err)
+	assert.EqualValues(t, 0, pool.Stat().TotalConns())
+}
+
 func TestPoolExec(t *testing.T) {
 	t.Parallel()
 
@@ -492,6 +878,60 @@ func TestPoolSendBatch(t *testing.T) {
 	assert.EqualValues(t, 1, stats.TotalConns())
 }
 
+func TestPoolDefaultQueryTimeout(t *testing.T) {
+	t.Parallel()
+
+	config, err := pgxpool.ParseConfig(os.Getenv("PGX_TEST_DATABASE"))
+	require.NoError(t, err)
+	config.DefaultQueryTimeout = 250 * time.Millisecond
+
+	pool, err := pgxpool.ConnectConfig(context.Background(), config)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	testExec(t, pool)
+	testQuery(t, pool)
+	testQueryRow(t, pool)
+	testSendBatch(t, pool)
+
+	_, err = pool.Exec(context.Background(), "select pg_sleep(2)")
+	assert.True(t, pgconn.Timeout(err), err)
+
+	var n int32
+	err = pool.QueryRow(context.Background(), "select 1 from pg_sleep(2)").Scan(&n)
+	assert.True(t, pgconn.Timeout(err), err)
+
+	// A shorter deadline on the caller's context takes precedence.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = pool.Exec(ctx, "select pg_sleep(1)")
+	assert.True(t, pgconn.Timeout(err), err)
+	assert.Less(t, int64(time.Since(start)), int64(250*time.Millisecond))
+
+	// The timeout applies to each statement in a transaction, not to the transaction as a whole.
+	tx, err := pool.Begin(context.Background())
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		_, err = tx.Exec(context.Background(), "select pg_sleep(0.1)")
+		require.NoError(t, err)
+	}
+	require.NoError(t, tx.Commit(context.Background()))
+
+	// Transactions started on an acquired Conn and pseudo nested transactions get the timeout too.
+	c, err := pool.Acquire(context.Background())
+	require.NoError(t, err)
+	defer c.Release()
+
+	tx, err = c.Begin(context.Background())
+	require.NoError(t, err)
+	nested, err := tx.Begin(context.Background())
+	require.NoError(t, err)
+	_, err = nested.Exec(context.Background(), "select pg_sleep(2)")
+	assert.True(t, pgconn.Timeout(err), err)
+	tx.Rollback(context.Background())
+}
+
 func TestPoolCopyFrom(t *testing.T) {
 	// Not able to use testCopyFrom because it relies on temporary tables and the pool may run subsequent calls under
 	// different connections.
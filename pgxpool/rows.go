@@ -1,6 +1,8 @@
 package pgxpool
 
 import (
+	"context"
+
 	"github.com/jackc/pgconn"
 	"github.com/jackc/pgproto3/v2"
 	"github.com/jackc/pgx/v4"
@@ -86,6 +88,38 @@ func (rows *poolRows) RawValues() [][]byte {
 	return rows.r.RawValues()
 }
 
+// cancelRows wraps a pgx.Rows and calls cancel when the rows are closed. It is used to release the context created for
+// Config.DefaultQueryTimeout.
+type cancelRows struct {
+	pgx.Rows
+	cancel context.CancelFunc
+}
+
+func (rows *cancelRows) Close() {
+	rows.Rows.Close()
+	rows.cancel()
+}
+
+func (rows *cancelRows) Next() bool {
+	n := rows.Rows.Next()
+	if !n {
+		rows.cancel()
+	}
+	return n
+}
+
+// cancelRow wraps a pgx.Row and calls cancel once the row has been scanned.
+type cancelRow struct {
+	r      pgx.Row
+	cancel context.CancelFunc
+}
+
+func (row *cancelRow) Scan(dest ...interface{}) error {
+	err := row.r.Scan(dest...)
+	row.cancel()
+	return err
+}
+
 type poolRow struct {
 	r   pgx.Row
 	c   *Conn
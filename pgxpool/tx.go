@@ -10,10 +10,16 @@ import (
 type Tx struct {
 	t pgx.Tx
 	c *Conn
+	p *Pool
 }
 
 func (tx *Tx) Begin(ctx context.Context) (pgx.Tx, error) {
-	return tx.t.Begin(ctx)
+	t, err := tx.t.Begin(ctx)
+	if err != nil || tx.p.defaultQueryTimeout <= 0 {
+		return t, err
+	}
+
+	return &Tx{t: t, p: tx.p}, nil
 }
 
 func (tx *Tx) BeginFunc(ctx context.Context, f func(pgx.Tx) error) error {
@@ -38,12 +44,30 @@ func (tx *Tx) Rollback(ctx context.Context) error {
 	return err
 }
 
+func (tx *Tx) Savepoint(ctx context.Context, name string) error {
+	return tx.t.Savepoint(ctx, name)
+}
+
+func (tx *Tx) RollbackToSavepoint(ctx context.Context, name string) error {
+	return tx.t.RollbackToSavepoint(ctx, name)
+}
+
+func (tx *Tx) ReleaseSavepoint(ctx context.Context, name string) error {
+	return tx.t.ReleaseSavepoint(ctx, name)
+}
+
 func (tx *Tx) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
 	return tx.t.CopyFrom(ctx, tableName, columnNames, rowSrc)
 }
 
 func (tx *Tx) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
-	return tx.t.SendBatch(ctx, b)
+	ctx, cancel := tx.p.queryContext(ctx)
+
+	br := tx.t.SendBatch(ctx, b)
+	if cancel == nil {
+		return br
+	}
+	return &cancelBatchResults{BatchResults: br, cancel: cancel}
 }
 
 func (tx *Tx) LargeObjects() pgx.LargeObjects {
@@ -55,18 +79,43 @@ func (tx *Tx) Prepare(ctx context.Context, name, sql string) (*pgconn.StatementD
 }
 
 func (tx *Tx) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
+	ctx, cancel := tx.p.queryContext(ctx)
+	if cancel != nil {
+		defer cancel()
+	}
+
 	return tx.t.Exec(ctx, sql, arguments...)
 }
 
 func (tx *Tx) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
-	return tx.t.Query(ctx, sql, args...)
+	ctx, cancel := tx.p.queryContext(ctx)
+
+	rows, err := tx.t.Query(ctx, sql, args...)
+	if cancel == nil {
+		return rows, err
+	}
+	if err != nil {
+		cancel()
+	}
+	return &cancelRows{Rows: rows, cancel: cancel}, err
 }
 
 func (tx *Tx) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
-	return tx.t.QueryRow(ctx, sql, args...)
+	ctx, cancel := tx.p.queryContext(ctx)
+
+	row := tx.t.QueryRow(ctx, sql, args...)
+	if cancel == nil {
+		return row
+	}
+	return &cancelRow{r: row, cancel: cancel}
 }
 
 func (tx *Tx) QueryFunc(ctx context.Context, sql string, args []interface{}, scans []interface{}, f func(pgx.QueryFuncRow) error) (pgconn.CommandTag, error) {
+	ctx, cancel := tx.p.queryContext(ctx)
+	if cancel != nil {
+		defer cancel()
+	}
+
 	return tx.t.QueryFunc(ctx, sql, args, scans, f)
 }
 
@@ -175,6 +175,50 @@ func TestConnQueryValues(t *testing.T) {
 	}
 }
 
+func TestConnQueryRowToMap(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	rows, err := conn.Query(context.Background(), "select 'foo'::text as name, n, null as missing from generate_series(1,$1) n", 3)
+	require.NoError(t, err)
+	defer rows.Close()
+
+	var rowCount int32
+	for rows.Next() {
+		rowCount++
+
+		m, err := pgx.RowToMap(rows)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"name": "foo", "n": rowCount, "missing": nil}, m)
+	}
+	require.NoError(t, rows.Err())
+	assert.EqualValues(t, 3, rowCount)
+}
+
+func TestConnCollectOneRowToMap(t *testing.T) {
+	t.Parallel()
+
+	testWithAndWithoutPreferSimpleProtocol(t, func(t *testing.T, conn *pgx.Conn) {
+		tm := time.Date(2021, 7, 10, 12, 30, 0, 0, time.UTC)
+		m, err := pgx.CollectOneRowToMap(conn.Query(context.Background(), "select 42::int4 as id, 'foo'::text as name, $1::timestamptz as created_at", tm))
+		require.NoError(t, err)
+		require.Len(t, m, 3)
+		assert.Equal(t, int32(42), m["id"])
+		assert.Equal(t, "foo", m["name"])
+		assert.True(t, tm.Equal(m["created_at"].(time.Time)))
+
+		m, err = pgx.CollectOneRowToMap(conn.Query(context.Background(), "select 1 as n where false"))
+		assert.Equal(t, pgx.ErrNoRows, err)
+		assert.Nil(t, m)
+
+		m, err = pgx.CollectOneRowToMap(conn.Query(context.Background(), "select 1/0 as n"))
+		assert.Error(t, err)
+		assert.Nil(t, m)
+	})
+}
+
 // https://github.com/jackc/pgx/issues/666
 func TestConnQueryValuesWhenUnableToDecode(t *testing.T) {
 	t.Parallel()
@@ -195,6 +239,239 @@ func TestConnQueryValuesWhenUnableToDecode(t *testing.T) {
 	require.Equal(t, "({1},)", values[0])
 }
 
+func TestConnQueryResultFormatMode(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	for _, tt := range []struct {
+		mode   pgx.QueryResultFormatMode
+		format int16
+	}{
+		{pgx.QueryResultFormatModePreferBinary, pgx.BinaryFormatCode},
+		{pgx.QueryResultFormatModePreferText, pgx.TextFormatCode},
+		{pgx.QueryResultFormatModeForceBinary, pgx.BinaryFormatCode},
+	} {
+		t.Run(tt.mode.String(), func(t *testing.T) {
+			rows, err := conn.Query(context.Background(), "select 1::int4, 'foo'::text", tt.mode)
+			require.NoError(t, err)
+			defer rows.Close()
+
+			for _, fd := range rows.FieldDescriptions() {
+				assert.Equal(t, tt.format, fd.Format)
+			}
+
+			require.True(t, rows.Next())
+			var n int32
+			var s string
+			require.NoError(t, rows.Scan(&n, &s))
+			assert.EqualValues(t, 1, n)
+			assert.Equal(t, "foo", s)
+
+			rows.Close()
+			require.NoError(t, rows.Err())
+		})
+	}
+
+	ensureConnValid(t, conn)
+}
+
+func TestConnQueryResultFormatModeForceBinaryUnsupportedType(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	// Unregistered types are only supported in the text format.
+	_, err := conn.Exec(context.Background(), "drop type if exists pgx_force_binary_mood; create type pgx_force_binary_mood as enum ('happy', 'sad');")
+	require.NoError(t, err)
+	defer conn.Exec(context.Background(), "drop type pgx_force_binary_mood")
+
+	rows, err := conn.Query(context.Background(), "select 1::int4 as n, 'happy'::pgx_force_binary_mood as mood", pgx.QueryResultFormatModeForceBinary)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "column 1 (mood)")
+	rows.Close()
+
+	ensureConnValid(t, conn)
+}
+
+func TestConnQueryResultFormatModeForceBinaryWithSimpleProtocol(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	_, err := conn.Query(context.Background(), "select 1", pgx.QueryResultFormatModeForceBinary, pgx.QuerySimpleProtocol(true))
+	require.Error(t, err)
+
+	ensureConnValid(t, conn)
+}
+
+func TestConnConfigTextFormatOIDs(t *testing.T) {
+	t.Parallel()
+
+	config := mustParseConfig(t, os.Getenv("PGX_TEST_DATABASE"))
+	config.TextFormatOIDs = []uint32{pgtype.NumericOID}
+	conn := mustConnect(t, config)
+	defer closeConn(t, conn)
+
+	rows, err := conn.Query(context.Background(), "select $1::numeric, 1::int4", 123.45)
+	require.NoError(t, err)
+	defer rows.Close()
+
+	fds := rows.FieldDescriptions()
+	assert.Equal(t, pgx.TextFormatCode, fds[0].Format)
+	assert.Equal(t, pgx.BinaryFormatCode, fds[1].Format)
+
+	require.True(t, rows.Next())
+	var s string
+	var n int32
+	require.NoError(t, rows.Scan(&s, &n))
+	assert.Equal(t, "123.45", s)
+	assert.EqualValues(t, 1, n)
+
+	rows.Close()
+	require.NoError(t, rows.Err())
+
+	// Explicit result formats take precedence.
+	rows, err = conn.Query(context.Background(), "select 1::numeric", pgx.QueryResultFormats{pgx.BinaryFormatCode})
+	require.NoError(t, err)
+	assert.Equal(t, pgx.BinaryFormatCode, rows.FieldDescriptions()[0].Format)
+	rows.Close()
+	require.NoError(t, rows.Err())
+
+	ensureConnValid(t, conn)
+}
+
+func TestConnQuerySkipStatementCache(t *testing.T) {
+	t.Parallel()
+
+	config := mustParseConfig(t, os.Getenv("PGX_TEST_DATABASE"))
+	config.BuildStatementCache = func(conn *pgconn.PgConn) stmtcache.Cache {
+		return stmtcache.New(conn, stmtcache.ModePrepare, 32)
+	}
+	conn := mustConnect(t, config)
+	defer closeConn(t, conn)
+
+	var n int64
+	err := conn.QueryRow(context.Background(), "select $1::int8", 1).Scan(&n)
+	require.NoError(t, err)
+	require.Equal(t, 1, conn.StatementCache().Len())
+
+	for i := 0; i < 10000; i++ {
+		sql := fmt.Sprintf("select $1::int8 + %d", i)
+		err := conn.QueryRow(context.Background(), sql, pgx.QuerySkipStatementCache(true), 1).Scan(&n)
+		require.NoError(t, err)
+		require.EqualValues(t, i+1, n)
+
+		_, err = conn.Exec(context.Background(), sql, pgx.QuerySkipStatementCache(true), 1)
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, 1, conn.StatementCache().Len())
+
+	// The originally cached statement is still usable.
+	err = conn.QueryRow(context.Background(), "select $1::int8", 2).Scan(&n)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, n)
+	require.Equal(t, 1, conn.StatementCache().Len())
+
+	ensureConnValid(t, conn)
+}
+
+func TestConnQueryNotices(t *testing.T) {
+	t.Parallel()
+
+	config := mustParseConfig(t, os.Getenv("PGX_TEST_DATABASE"))
+	var onNoticeCount int
+	config.OnNotice = func(*pgconn.PgConn, *pgconn.Notice) {
+		onNoticeCount++
+	}
+
+	conn := mustConnect(t, config)
+	defer closeConn(t, conn)
+
+	skipCockroachDB(t, conn, "Server does not support RAISE NOTICE")
+
+	mustExec(t, conn, `create function pg_temp.pgx_notice(n int4) returns int4 language plpgsql as $$
+begin
+	raise notice 'row %', n;
+	return n;
+end;
+$$`)
+
+	var execNotices pgx.QueryNotices
+	_, err := conn.Exec(context.Background(), "do $$ begin raise notice 'first'; raise warning 'second'; end $$", &execNotices)
+	require.NoError(t, err)
+	require.Len(t, execNotices.Notices, 2)
+	require.Equal(t, "first", execNotices.Notices[0].Message)
+	require.Equal(t, "NOTICE", execNotices.Notices[0].Severity)
+	require.Equal(t, "second", execNotices.Notices[1].Message)
+	require.Equal(t, "WARNING", execNotices.Notices[1].Severity)
+
+	var queryNotices pgx.QueryNotices
+	rows, err := conn.Query(context.Background(), "select pg_temp.pgx_notice(n) from generate_series(1, $1) n", &queryNotices, 3)
+	require.NoError(t, err)
+	var sum int32
+	for rows.Next() {
+		var n int32
+		require.NoError(t, rows.Scan(&n))
+		sum += n
+	}
+	require.NoError(t, rows.Err())
+	require.EqualValues(t, 6, sum)
+	require.Len(t, queryNotices.Notices, 3)
+	for i, n := range queryNotices.Notices {
+		require.Equal(t, fmt.Sprintf("row %d", i+1), n.Message)
+	}
+
+	// Notices of later queries are not collected.
+	_, err = conn.Exec(context.Background(), "do $$ begin raise notice 'third'; end $$")
+	require.NoError(t, err)
+	require.Len(t, execNotices.Notices, 2)
+	require.Len(t, queryNotices.Notices, 3)
+
+	require.Equal(t, 6, onNoticeCount)
+
+	ensureConnValid(t, conn)
+}
+
+func TestConnQueryScanNullAsZero(t *testing.T) {
+	t.Parallel()
+
+	testWithAndWithoutPreferSimpleProtocol(t, func(t *testing.T, conn *pgx.Conn) {
+		n := 42
+		s := "foo"
+		tm := time.Now()
+		p := &n
+		err := conn.QueryRow(context.Background(), "select null::int4, null::text, null::timestamptz, null::int4").Scan(
+			pgx.NullAsZero(&n), pgx.NullAsZero(&s), pgx.NullAsZero(&tm), pgx.NullAsZero(&p),
+		)
+		require.NoError(t, err)
+		require.Equal(t, 0, n)
+		require.Equal(t, "", s)
+		require.True(t, tm.IsZero())
+		require.Nil(t, p)
+
+		err = conn.QueryRow(context.Background(), "select 7::int4, 'bar'::text, 8::int4").Scan(pgx.NullAsZero(&n), pgx.NullAsZero(&s), pgx.NullAsZero(&p))
+		require.NoError(t, err)
+		require.Equal(t, 7, n)
+		require.Equal(t, "bar", s)
+		require.NotNil(t, p)
+		require.Equal(t, 8, *p)
+
+		// Without NullAsZero a NULL cannot be scanned into an int.
+		err = conn.QueryRow(context.Background(), "select null::int4").Scan(&n)
+		require.Error(t, err)
+
+		err = conn.QueryRow(context.Background(), "select null::int4").Scan(pgx.NullAsZero(n))
+		require.Error(t, err)
+
+		ensureConnValid(t, conn)
+	})
+}
+
 func TestConnQueryValuesWithUnknownOID(t *testing.T) {
 	t.Parallel()
 
@@ -222,6 +499,34 @@ func TestConnQueryValuesWithUnknownOID(t *testing.T) {
 	require.Equal(t, "orange", values[0])
 }
 
+func TestConnQueryCommandTagRowsAffectedForSelect(t *testing.T) {
+	t.Parallel()
+
+	testWithAndWithoutPreferSimpleProtocol(t, func(t *testing.T, conn *pgx.Conn) {
+		rows, err := conn.Query(context.Background(), "select n from generate_series(1, $1) n", 42)
+		require.NoError(t, err)
+
+		var rowCount int64
+		for rows.Next() {
+			rowCount++
+		}
+		require.NoError(t, rows.Err())
+		assert.EqualValues(t, 42, rowCount)
+		assert.Equal(t, rowCount, rows.CommandTag().RowsAffected())
+		assert.True(t, rows.CommandTag().Select())
+
+		// Rows that were not read are counted as well.
+		rows, err = conn.Query(context.Background(), "select n from generate_series(1, $1) n", 42)
+		require.NoError(t, err)
+		require.True(t, rows.Next())
+		rows.Close()
+		require.NoError(t, rows.Err())
+		assert.EqualValues(t, 42, rows.CommandTag().RowsAffected())
+
+		ensureConnValid(t, conn)
+	})
+}
+
 // https://github.com/jackc/pgx/issues/478
 func TestConnQueryReadRowMultipleTimes(t *testing.T) {
 	t.Parallel()
@@ -417,6 +722,39 @@ func TestConnQueryCloseEarly(t *testing.T) {
 	ensureConnValid(t, conn)
 }
 
+func TestConnQueryWhileRowsNotClosed(t *testing.T) {
+	t.Parallel()
+
+	testWithAndWithoutPreferSimpleProtocol(t, func(t *testing.T, conn *pgx.Conn) {
+		rows, err := conn.Query(context.Background(), "select generate_series(1,$1)", 10)
+		require.NoError(t, err)
+		require.True(t, rows.Next())
+
+		_, err = conn.Exec(context.Background(), "select 1")
+		require.Error(t, err)
+		require.True(t, errors.Is(err, pgx.ErrConnBusy))
+		var busyErr *pgx.ConnBusyError
+		require.True(t, errors.As(err, &busyErr))
+		require.Contains(t, busyErr.PriorSQL, "generate_series")
+
+		var n int32
+		err = conn.QueryRow(context.Background(), "select 1").Scan(&n)
+		require.True(t, errors.Is(err, pgx.ErrConnBusy))
+
+		br := conn.SendBatch(context.Background(), &pgx.Batch{})
+		require.True(t, errors.Is(br.Close(), pgx.ErrConnBusy))
+
+		// The unfinished query is unaffected and the connection is usable once it is closed.
+		require.NoError(t, rows.Scan(&n))
+		require.EqualValues(t, 1, n)
+		rows.Close()
+		require.NoError(t, rows.Err())
+		require.False(t, conn.IsClosed())
+
+		ensureConnValid(t, conn)
+	})
+}
+
 func TestConnQueryCloseEarlyWithErrorOnWire(t *testing.T) {
 	t.Parallel()
 
@@ -1318,6 +1656,35 @@ func TestConnQueryDatabaseSQLNullX(t *testing.T) {
 	ensureConnValid(t, conn)
 }
 
+func TestConnQueryDatabaseSQLNullInt32AndNullTime(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	expectedTime := time.Date(2021, 3, 4, 5, 6, 7, 8000, time.FixedZone("", -5*60*60))
+
+	var int32Valid, int32Null sql.NullInt32
+	var timeValid, timeNull sql.NullTime
+
+	err := conn.QueryRow(
+		context.Background(),
+		"select 42::int4, null::int4, $1::timestamptz, null::timestamptz",
+		expectedTime,
+	).Scan(&int32Valid, &int32Null, &timeValid, &timeNull)
+	require.NoError(t, err)
+
+	assert.Equal(t, sql.NullInt32{Int32: 42, Valid: true}, int32Valid)
+	assert.Equal(t, sql.NullInt32{}, int32Null)
+
+	// timestamptz stores an instant, not an offset, so only the instant can be compared.
+	require.True(t, timeValid.Valid)
+	assert.True(t, expectedTime.Equal(timeValid.Time), "expected %v, got %v", expectedTime, timeValid.Time)
+	assert.Equal(t, sql.NullTime{}, timeNull)
+
+	ensureConnValid(t, conn)
+}
+
 func TestQueryContextSuccess(t *testing.T) {
 	t.Parallel()
 
@@ -1467,6 +1834,15 @@ func TestScanRow(t *testing.T) {
 	require.NoError(t, err)
 	assert.EqualValues(t, 10, rowCount)
 	assert.EqualValues(t, 55, sum)
+
+	resultReader = conn.PgConn().ExecParams(context.Background(), "select null::int4", nil, nil, nil, nil)
+	require.True(t, resultReader.NextRow())
+	n := int32(42)
+	err = pgx.ScanRow(conn.ConnInfo(), resultReader.FieldDescriptions(), resultReader.Values(), pgx.NullAsZero(&n))
+	require.NoError(t, err)
+	assert.EqualValues(t, 0, n)
+	_, err = resultReader.Close()
+	require.NoError(t, err)
 }
 
 func TestConnSimpleProtocol(t *testing.T) {
@@ -2077,6 +2453,34 @@ func TestConnQueryFuncAbort(t *testing.T) {
 	})
 }
 
+func TestConnQueryFuncStop(t *testing.T) {
+	t.Parallel()
+
+	testWithAndWithoutPreferSimpleProtocol(t, func(t *testing.T, conn *pgx.Conn) {
+		var actualResults []int
+
+		var n int
+		ct, err := conn.QueryFunc(
+			context.Background(),
+			"select n from generate_series(1, $1) n",
+			[]interface{}{10},
+			[]interface{}{&n},
+			func(pgx.QueryFuncRow) error {
+				actualResults = append(actualResults, n)
+				if len(actualResults) == 2 {
+					return pgx.ErrStopQueryFunc
+				}
+				return nil
+			},
+		)
+		require.NoError(t, err)
+		require.Equal(t, []int{1, 2}, actualResults)
+		require.EqualValues(t, 10, ct.RowsAffected())
+
+		ensureConnValid(t, conn)
+	})
+}
+
 func ExampleConn_QueryFunc() {
 	conn, err := pgx.Connect(context.Background(), os.Getenv("PGX_TEST_DATABASE"))
 	if err != nil {
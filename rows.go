@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"reflect"
 	"time"
 
 	"github.com/jackc/pgconn"
@@ -29,7 +30,9 @@ type Rows interface {
 	// Err returns any error that occurred while reading.
 	Err() error
 
-	// CommandTag returns the command tag from this query. It is only available after Rows is closed.
+	// CommandTag returns the command tag from this query. It is only available after Rows is closed. For a select
+	// statement its RowsAffected is the number of rows returned by the query, including any rows that were not read
+	// before Rows was closed.
 	CommandTag() pgconn.CommandTag
 
 	FieldDescriptions() []pgproto3.FieldDescription
@@ -90,6 +93,7 @@ func (r *connRow) Scan(dest ...interface{}) (err error) {
 type rowLog interface {
 	shouldLog(lvl LogLevel) bool
 	log(ctx context.Context, lvl LogLevel, msg string, data map[string]interface{})
+	logArgs(sql string, args []interface{}) interface{}
 }
 
 // connRows implements the Rows interface for Conn.Query.
@@ -106,6 +110,7 @@ type connRows struct {
 	args       []interface{}
 	closed     bool
 	conn       *Conn
+	notices    *QueryNotices
 
 	resultReader      *pgconn.ResultReader
 	multiResultReader *pgconn.MultiResultReader
@@ -124,6 +129,10 @@ func (rows *connRows) Close() {
 
 	rows.closed = true
 
+	if rows.conn != nil && rows.conn.busyRows == rows {
+		rows.conn.busyRows = nil
+	}
+
 	if rows.resultReader != nil {
 		var closeErr error
 		rows.commandTag, closeErr = rows.resultReader.Close()
@@ -139,15 +148,19 @@ func (rows *connRows) Close() {
 		}
 	}
 
+	if rows.notices != nil && rows.conn.notices == rows.notices {
+		rows.conn.notices = nil
+	}
+
 	if rows.logger != nil {
 		if rows.err == nil {
 			if rows.logger.shouldLog(LogLevelInfo) {
 				endTime := time.Now()
-				rows.logger.log(rows.ctx, LogLevelInfo, "Query", map[string]interface{}{"sql": rows.sql, "args": logQueryArgs(rows.args), "time": endTime.Sub(rows.startTime), "rowCount": rows.rowCount})
+				rows.logger.log(rows.ctx, LogLevelInfo, "Query", map[string]interface{}{"sql": rows.sql, "args": rows.logger.logArgs(rows.sql, rows.args), "time": endTime.Sub(rows.startTime), "rowCount": rows.rowCount})
 			}
 		} else {
 			if rows.logger.shouldLog(LogLevelError) {
-				rows.logger.log(rows.ctx, LogLevelError, "Query", map[string]interface{}{"err": rows.err, "sql": rows.sql, "args": logQueryArgs(rows.args)})
+				rows.logger.log(rows.ctx, LogLevelError, "Query", map[string]interface{}{"err": rows.err, "sql": rows.sql, "args": rows.logger.logArgs(rows.sql, rows.args)})
 			}
 			if rows.err != nil && rows.conn.stmtcache != nil {
 				rows.conn.stmtcache.StatementErrored(rows.sql, rows.err)
@@ -207,23 +220,13 @@ func (rows *connRows) Scan(dest ...interface{}) error {
 	}
 
 	if rows.scanPlans == nil {
-		rows.scanPlans = make([]pgtype.ScanPlan, len(values))
-		for i := range dest {
-			rows.scanPlans[i] = ci.PlanScan(fieldDescriptions[i].DataTypeOID, fieldDescriptions[i].Format, dest[i])
-		}
+		rows.scanPlans = planScans(ci, fieldDescriptions, dest)
 	}
 
-	for i, dst := range dest {
-		if dst == nil {
-			continue
-		}
-
-		err := rows.scanPlans[i].Scan(ci, fieldDescriptions[i].DataTypeOID, fieldDescriptions[i].Format, values[i], dst)
-		if err != nil {
-			err = ScanArgError{ColumnIndex: i, Err: err}
-			rows.fatal(err)
-			return err
-		}
+	err := scanValues(ci, fieldDescriptions, values, rows.scanPlans, dest)
+	if err != nil {
+		rows.fatal(err)
+		return err
 	}
 
 	return nil
@@ -234,18 +237,30 @@ func (rows *connRows) Values() ([]interface{}, error) {
 		return nil, errors.New("rows is closed")
 	}
 
-	values := make([]interface{}, 0, len(rows.FieldDescriptions()))
+	values, err := decodeRowValues(rows.connInfo, rows.FieldDescriptions(), rows.values)
+	if err != nil {
+		rows.fatal(err)
+		return nil, rows.Err()
+	}
+
+	return values, rows.Err()
+}
+
+// decodeRowValues decodes the raw values of a row into the default Go type of the data type registered for each
+// column's OID.
+func decodeRowValues(ci *pgtype.ConnInfo, fieldDescriptions []pgproto3.FieldDescription, rawValues [][]byte) ([]interface{}, error) {
+	values := make([]interface{}, 0, len(fieldDescriptions))
 
-	for i := range rows.FieldDescriptions() {
-		buf := rows.values[i]
-		fd := &rows.FieldDescriptions()[i]
+	for i := range fieldDescriptions {
+		buf := rawValues[i]
+		fd := &fieldDescriptions[i]
 
 		if buf == nil {
 			values = append(values, nil)
 			continue
 		}
 
-		if dt, ok := rows.connInfo.DataTypeForOID(fd.DataTypeOID); ok {
+		if dt, ok := ci.DataTypeForOID(fd.DataTypeOID); ok {
 			value := dt.Value
 
 			switch fd.Format {
@@ -254,9 +269,9 @@ func (rows *connRows) Values() ([]interface{}, error) {
 				if !ok {
 					decoder = &pgtype.GenericText{}
 				}
-				err := decoder.DecodeText(rows.connInfo, buf)
+				err := decoder.DecodeText(ci, buf)
 				if err != nil {
-					rows.fatal(err)
+					return nil, err
 				}
 				values = append(values, decoder.(pgtype.Value).Get())
 			case BinaryFormatCode:
@@ -264,47 +279,72 @@ func (rows *connRows) Values() ([]interface{}, error) {
 				if !ok {
 					decoder = &pgtype.GenericBinary{}
 				}
-				err := decoder.DecodeBinary(rows.connInfo, buf)
+				err := decoder.DecodeBinary(ci, buf)
 				if err != nil {
-					rows.fatal(err)
+					return nil, err
 				}
 				values = append(values, value.Get())
 			default:
-				rows.fatal(errors.New("Unknown format code"))
+				return nil, errors.New("Unknown format code")
 			}
 		} else {
 			switch fd.Format {
 			case TextFormatCode:
 				decoder := &pgtype.GenericText{}
-				err := decoder.DecodeText(rows.connInfo, buf)
+				err := decoder.DecodeText(ci, buf)
 				if err != nil {
-					rows.fatal(err)
+					return nil, err
 				}
 				values = append(values, decoder.Get())
 			case BinaryFormatCode:
 				decoder := &pgtype.GenericBinary{}
-				err := decoder.DecodeBinary(rows.connInfo, buf)
+				err := decoder.DecodeBinary(ci, buf)
 				if err != nil {
-					rows.fatal(err)
+					return nil, err
 				}
 				values = append(values, decoder.Get())
 			default:
-				rows.fatal(errors.New("Unknown format code"))
+				return nil, errors.New("Unknown format code")
 			}
 		}
-
-		if rows.Err() != nil {
-			return nil, rows.Err()
-		}
 	}
 
-	return values, rows.Err()
+	return values, nil
 }
 
 func (rows *connRows) RawValues() [][]byte {
 	return rows.values
 }
 
+// NullAsZero wraps the scan destination dst, which must be a pointer, so that scanning a NULL sets it to its zero value
+// instead of returning an error. e.g. A NULL scanned into NullAsZero(&n) where n is an int sets n to 0, and a NULL
+// scanned into NullAsZero(&p) where p is a *int sets p to nil. This loses the distinction between NULL and the zero
+// value, so it should only be used where that does not matter. Non-NULL values are scanned into dst as usual.
+// NullAsZero can be used with Rows.Scan, Row.Scan, and ScanRow.
+func NullAsZero(dst interface{}) interface{} {
+	return &nullAsZero{dst: dst}
+}
+
+type nullAsZero struct {
+	dst interface{}
+}
+
+func (nz *nullAsZero) setZero() error {
+	v := reflect.ValueOf(nz.dst)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("NullAsZero destination must be a non-nil pointer, got %T", nz.dst)
+	}
+	v.Elem().Set(reflect.Zero(v.Elem().Type()))
+	return nil
+}
+
+func unwrapNullAsZero(dst interface{}) interface{} {
+	if nz, ok := dst.(*nullAsZero); ok {
+		return nz.dst
+	}
+	return dst
+}
+
 type ScanArgError struct {
 	ColumnIndex int
 	Err         error
@@ -332,12 +372,43 @@ func ScanRow(connInfo *pgtype.ConnInfo, fieldDescriptions []pgproto3.FieldDescri
 		return fmt.Errorf("number of field descriptions must equal number of destinations, got %d and %d", len(fieldDescriptions), len(dest))
 	}
 
-	for i, d := range dest {
-		if d == nil {
+	return scanValues(connInfo, fieldDescriptions, values, nil, dest)
+}
+
+// planScans returns a scan plan for each destination in dest.
+func planScans(ci *pgtype.ConnInfo, fieldDescriptions []pgproto3.FieldDescription, dest []interface{}) []pgtype.ScanPlan {
+	scanPlans := make([]pgtype.ScanPlan, len(dest))
+	for i := range dest {
+		scanPlans[i] = ci.PlanScan(fieldDescriptions[i].DataTypeOID, fieldDescriptions[i].Format, unwrapNullAsZero(dest[i]))
+	}
+	return scanPlans
+}
+
+// scanValues scans values into dest. A nil destination is skipped and a NullAsZero destination is unwrapped. If
+// scanPlans is nil each value is scanned with ci.Scan. Otherwise it must contain a plan for each destination.
+func scanValues(ci *pgtype.ConnInfo, fieldDescriptions []pgproto3.FieldDescription, values [][]byte, scanPlans []pgtype.ScanPlan, dest []interface{}) error {
+	for i, dst := range dest {
+		if dst == nil {
 			continue
 		}
 
-		err := connInfo.Scan(fieldDescriptions[i].DataTypeOID, fieldDescriptions[i].Format, values[i], d)
+		if nz, ok := dst.(*nullAsZero); ok {
+			if values[i] == nil {
+				err := nz.setZero()
+				if err != nil {
+					return ScanArgError{ColumnIndex: i, Err: err}
+				}
+				continue
+			}
+			dst = nz.dst
+		}
+
+		var err error
+		if scanPlans == nil {
+			err = ci.Scan(fieldDescriptions[i].DataTypeOID, fieldDescriptions[i].Format, values[i], dst)
+		} else {
+			err = scanPlans[i].Scan(ci, fieldDescriptions[i].DataTypeOID, fieldDescriptions[i].Format, values[i], dst)
+		}
 		if err != nil {
 			return ScanArgError{ColumnIndex: i, Err: err}
 		}
@@ -345,3 +416,49 @@ func ScanRow(connInfo *pgtype.ConnInfo, fieldDescriptions []pgproto3.FieldDescri
 
 	return nil
 }
+
+// RowToMap returns the current row of rows as a map from column name to value. Values are decoded the same as
+// Rows.Values: each column uses the default Go type of the data type registered for its OID (e.g. int4 -> int32, text
+// -> string, timestamptz -> time.Time). Columns with an OID that is not registered decode to a string when in the text
+// format and to a []byte when in the binary format. If multiple columns have the same name the last one wins.
+func RowToMap(rows Rows) (map[string]interface{}, error) {
+	values, err := rows.Values()
+	if err != nil {
+		return nil, err
+	}
+
+	fieldDescriptions := rows.FieldDescriptions()
+	m := make(map[string]interface{}, len(fieldDescriptions))
+	for i := range fieldDescriptions {
+		m[string(fieldDescriptions[i].Name)] = values[i]
+	}
+
+	return m, nil
+}
+
+// CollectOneRowToMap reads the first row from rows, converts it with RowToMap, and closes rows. It returns ErrNoRows
+// if no rows were returned. Any rows after the first are ignored like with QueryRow. It is intended to wrap a call to
+// Query:
+//
+//   m, err := pgx.CollectOneRowToMap(conn.Query(ctx, "select * from widgets where id=$1", 42))
+func CollectOneRowToMap(rows Rows, err error) (map[string]interface{}, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if rows.Err() != nil {
+			return nil, rows.Err()
+		}
+		return nil, ErrNoRows
+	}
+
+	m, err := RowToMap(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	rows.Close()
+	return m, rows.Err()
+}
@@ -5,7 +5,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"regexp"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgconn"
 )
@@ -70,6 +73,28 @@ var ErrTxClosed = errors.New("tx is closed")
 // it is treated as ROLLBACK.
 var ErrTxCommitRollback = errors.New("commit unexpectedly resulted in rollback")
 
+// ErrSavepointNotFound occurs when a named savepoint is released or rolled back to but it was never created in the
+// transaction or has already been destroyed by releasing or rolling back to an earlier savepoint.
+var ErrSavepointNotFound = errors.New("savepoint does not exist")
+
+var savepointNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_$]*$`)
+var pseudoNestedSavepointNameRegexp = regexp.MustCompile(`^sp_[0-9]+$`)
+
+// validateSavepointName returns an error if name is not a valid unquoted SQL identifier or collides with the names
+// used for pseudo nested transactions.
+func validateSavepointName(name string) error {
+	if len(name) > 63 {
+		return fmt.Errorf("savepoint name %q is longer than 63 characters", name)
+	}
+	if !savepointNameRegexp.MatchString(name) {
+		return fmt.Errorf("savepoint name %q must start with a letter or underscore and contain only letters, digits, underscores, and dollar signs", name)
+	}
+	if pseudoNestedSavepointNameRegexp.MatchString(name) {
+		return fmt.Errorf("savepoint name %q is reserved for pseudo nested transactions", name)
+	}
+	return nil
+}
+
 // Begin starts a transaction. Unlike database/sql, the context only affects the begin command. i.e. there is no
 // auto-rollback on context cancellation.
 func (c *Conn) Begin(ctx context.Context) (Tx, error) {
@@ -123,6 +148,78 @@ func (c *Conn) BeginTxFunc(ctx context.Context, txOptions TxOptions, f func(Tx)
 	return tx.Commit(ctx)
 }
 
+// TxRetryPolicy controls how BeginTxFuncWithRetry retries a transaction.
+type TxRetryPolicy struct {
+	// MaxAttempts is the maximum number of times the transaction is run including the first attempt. Default: 3.
+	MaxAttempts int
+
+	// RetryDelay is the duration to wait before the first retry. Default: 0 (retry immediately).
+	RetryDelay time.Duration
+
+	// MaxRetryDelay enables exponential backoff when it is greater than RetryDelay. The delay starts at RetryDelay and
+	// doubles after each retry up to MaxRetryDelay. Default: 0 (constant delay).
+	MaxRetryDelay time.Duration
+}
+
+func (p TxRetryPolicy) maxAttempts() int {
+	if p.MaxAttempts < 1 {
+		return 3
+	}
+	return p.MaxAttempts
+}
+
+// retryDelay returns the delay before the retry that follows attempt.
+func (p TxRetryPolicy) retryDelay(attempt int) time.Duration {
+	delay := p.RetryDelay
+	for i := 1; i < attempt && delay < p.MaxRetryDelay; i++ {
+		delay *= 2
+	}
+	if p.MaxRetryDelay > p.RetryDelay && delay > p.MaxRetryDelay {
+		delay = p.MaxRetryDelay
+	}
+	return delay
+}
+
+// BeginTxFuncWithRetry runs f in a transaction with db.BeginTxFunc and runs it again in a new transaction when it
+// fails with a serialization failure (SQLSTATE 40001) or a deadlock (SQLSTATE 40P01). These errors are expected under
+// the serializable and repeatable read isolation levels and the transaction can succeed when retried. db can be a
+// *Conn, a *pgxpool.Conn, or a *pgxpool.Pool, which acquires a connection for each attempt.
+//
+// The error may come from a statement run by f or from the commit. f must return errors from statements, possibly
+// wrapped, for them to be detected. As f may be called more than once it must be idempotent: it must not have side
+// effects outside of the transaction, or those effects must be safe to repeat.
+//
+// It returns the number of attempts made along with the error of the last attempt. Other errors are returned
+// immediately without retrying. Waiting between attempts is interrupted when ctx is canceled.
+func BeginTxFuncWithRetry(ctx context.Context, db interface {
+	BeginTxFunc(ctx context.Context, txOptions TxOptions, f func(Tx) error) error
+}, txOptions TxOptions, policy TxRetryPolicy, f func(Tx) error) (attempts int, err error) {
+	maxAttempts := policy.maxAttempts()
+	for {
+		attempts++
+		err = db.BeginTxFunc(ctx, txOptions, f)
+		if err == nil || !isRetryableTxError(err) || attempts >= maxAttempts {
+			return attempts, err
+		}
+
+		delay := policy.retryDelay(attempts)
+		if delay > 0 {
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return attempts, ctx.Err()
+			case <-timer.C:
+			}
+		}
+	}
+}
+
+func isRetryableTxError(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && (pgErr.Code == "40001" || pgErr.Code == "40P01")
+}
+
 // Tx represents a database transaction.
 //
 // Tx is an interface instead of a struct to enable connection pools to be implemented without relying on internal pgx
@@ -161,6 +258,27 @@ type Tx interface {
 	QueryRow(ctx context.Context, sql string, args ...interface{}) Row
 	QueryFunc(ctx context.Context, sql string, args []interface{}, scans []interface{}, f func(QueryFuncRow) error) (pgconn.CommandTag, error)
 
+	// Savepoint creates a savepoint named name. name must be a valid unquoted SQL identifier. Like an unquoted
+	// identifier it is case insensitive: it is folded to lower case, so Savepoint(ctx, "Foo") creates the same savepoint
+	// as the SQL savepoint foo. The savepoint remains until it is released, the transaction ends, or an earlier
+	// savepoint is released or rolled back to.
+	//
+	// The Tx keeps a stack of the savepoints created by Savepoint and by Begin for pseudo nested transactions.
+	// RollbackToSavepoint and ReleaseSavepoint only find savepoints on this stack, so a savepoint created with Exec is
+	// not found by them and must be rolled back to or released with Exec as well.
+	Savepoint(ctx context.Context, name string) error
+
+	// RollbackToSavepoint rolls back to the most recent savepoint named name. The savepoint remains and can be rolled
+	// back to again. Any savepoints created after it are destroyed, including those of pseudo nested transactions begun
+	// after it. Commit or Rollback of such a pseudo nested transaction then returns ErrSavepointNotFound without sending
+	// anything to the server. ErrSavepointNotFound is returned if the savepoint is not on the stack.
+	RollbackToSavepoint(ctx context.Context, name string) error
+
+	// ReleaseSavepoint releases the most recent savepoint named name and any savepoints created after it, with the same
+	// effect on pseudo nested transactions as RollbackToSavepoint. ErrSavepointNotFound is returned if the savepoint is
+	// not on the stack.
+	ReleaseSavepoint(ctx context.Context, name string) error
+
 	// Conn returns the underlying *Conn that on which this transaction is executing.
 	Conn() *Conn
 }
@@ -174,6 +292,9 @@ type dbTx struct {
 	err          error
 	savepointNum int64
 	closed       bool
+
+	// savepoints is the stack of open savepoints, both named and those of pseudo nested transactions.
+	savepoints []string
 }
 
 // Begin starts a pseudo nested transaction implemented with a savepoint.
@@ -183,14 +304,106 @@ func (tx *dbTx) Begin(ctx context.Context) (Tx, error) {
 	}
 
 	tx.savepointNum++
-	_, err := tx.conn.Exec(ctx, "savepoint sp_"+strconv.FormatInt(tx.savepointNum, 10))
+	name := "sp_" + strconv.FormatInt(tx.savepointNum, 10)
+	_, err := tx.conn.Exec(ctx, "savepoint "+name)
 	if err != nil {
 		return nil, err
 	}
+	tx.savepoints = append(tx.savepoints, name)
 
 	return &dbSavepoint{tx: tx, savepointNum: tx.savepointNum}, nil
 }
 
+// Savepoint creates a named savepoint.
+func (tx *dbTx) Savepoint(ctx context.Context, name string) error {
+	if tx.closed {
+		return ErrTxClosed
+	}
+
+	name = strings.ToLower(name)
+	err := validateSavepointName(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.conn.Exec(ctx, "savepoint "+Identifier{name}.Sanitize())
+	if err != nil {
+		return err
+	}
+	tx.savepoints = append(tx.savepoints, name)
+
+	return nil
+}
+
+// RollbackToSavepoint rolls back to a named savepoint.
+func (tx *dbTx) RollbackToSavepoint(ctx context.Context, name string) error {
+	if tx.closed {
+		return ErrTxClosed
+	}
+
+	name = strings.ToLower(name)
+	err := validateSavepointName(name)
+	if err != nil {
+		return err
+	}
+
+	return tx.rollbackToSavepoint(ctx, name, Identifier{name}.Sanitize())
+}
+
+// ReleaseSavepoint releases a named savepoint.
+func (tx *dbTx) ReleaseSavepoint(ctx context.Context, name string) error {
+	if tx.closed {
+		return ErrTxClosed
+	}
+
+	name = strings.ToLower(name)
+	err := validateSavepointName(name)
+	if err != nil {
+		return err
+	}
+
+	return tx.releaseSavepoint(ctx, name, Identifier{name}.Sanitize())
+}
+
+func (tx *dbTx) findSavepoint(name string) int {
+	for i := len(tx.savepoints) - 1; i >= 0; i-- {
+		if tx.savepoints[i] == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (tx *dbTx) rollbackToSavepoint(ctx context.Context, name, quotedName string) error {
+	idx := tx.findSavepoint(name)
+	if idx < 0 {
+		return fmt.Errorf("rollback to savepoint %s: %w", name, ErrSavepointNotFound)
+	}
+
+	_, err := tx.conn.Exec(ctx, "rollback to savepoint "+quotedName)
+	if err != nil {
+		return err
+	}
+	tx.savepoints = tx.savepoints[:idx+1]
+
+	return nil
+}
+
+func (tx *dbTx) releaseSavepoint(ctx context.Context, name, quotedName string) error {
+	idx := tx.findSavepoint(name)
+	if idx < 0 {
+		return fmt.Errorf("release savepoint %s: %w", name, ErrSavepointNotFound)
+	}
+
+	_, err := tx.conn.Exec(ctx, "release savepoint "+quotedName)
+	if err != nil {
+		return err
+	}
+	tx.savepoints = tx.savepoints[:idx]
+
+	return nil
+}
+
 func (tx *dbTx) BeginFunc(ctx context.Context, f func(Tx) error) (err error) {
 	if tx.closed {
 		return ErrTxClosed
@@ -327,7 +540,7 @@ func (tx *dbTx) Conn() *Conn {
 
 // dbSavepoint represents a nested transaction implemented by a savepoint.
 type dbSavepoint struct {
-	tx           Tx
+	tx           *dbTx
 	savepointNum int64
 	closed       bool
 }
@@ -355,7 +568,8 @@ func (sp *dbSavepoint) Commit(ctx context.Context) error {
 		return ErrTxClosed
 	}
 
-	_, err := sp.Exec(ctx, "release savepoint sp_"+strconv.FormatInt(sp.savepointNum, 10))
+	name := "sp_" + strconv.FormatInt(sp.savepointNum, 10)
+	err := sp.tx.releaseSavepoint(ctx, name, name)
 	sp.closed = true
 	return err
 }
@@ -368,11 +582,39 @@ func (sp *dbSavepoint) Rollback(ctx context.Context) error {
 		return ErrTxClosed
 	}
 
-	_, err := sp.Exec(ctx, "rollback to savepoint sp_"+strconv.FormatInt(sp.savepointNum, 10))
+	name := "sp_" + strconv.FormatInt(sp.savepointNum, 10)
+	err := sp.tx.rollbackToSavepoint(ctx, name, name)
 	sp.closed = true
 	return err
 }
 
+// Savepoint delegates to the underlying Tx
+func (sp *dbSavepoint) Savepoint(ctx context.Context, name string) error {
+	if sp.closed {
+		return ErrTxClosed
+	}
+
+	return sp.tx.Savepoint(ctx, name)
+}
+
+// RollbackToSavepoint delegates to the underlying Tx
+func (sp *dbSavepoint) RollbackToSavepoint(ctx context.Context, name string) error {
+	if sp.closed {
+		return ErrTxClosed
+	}
+
+	return sp.tx.RollbackToSavepoint(ctx, name)
+}
+
+// ReleaseSavepoint delegates to the underlying Tx
+func (sp *dbSavepoint) ReleaseSavepoint(ctx context.Context, name string) error {
+	if sp.closed {
+		return ErrTxClosed
+	}
+
+	return sp.tx.ReleaseSavepoint(ctx, name)
+}
+
 // Exec delegates to the underlying Tx
 func (sp *dbSavepoint) Exec(ctx context.Context, sql string, arguments ...interface{}) (commandTag pgconn.CommandTag, err error) {
 	if sp.closed {
@@ -353,6 +353,74 @@ func TestBeginFuncRollbackOnError(t *testing.T) {
 	require.EqualValues(t, 0, n)
 }
 
+func TestBeginTxFuncWithRetry(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	mustExec(t, conn, "create temporary table foo(id integer primary key)")
+
+	calls := 0
+	attempts, err := pgx.BeginTxFuncWithRetry(context.Background(), conn, pgx.TxOptions{IsoLevel: pgx.Serializable}, pgx.TxRetryPolicy{RetryDelay: time.Millisecond, MaxRetryDelay: 5 * time.Millisecond}, func(tx pgx.Tx) error {
+		calls++
+		_, err := tx.Exec(context.Background(), "insert into foo(id) values (1)")
+		if err != nil {
+			return err
+		}
+		if calls < 3 {
+			_, err = tx.Exec(context.Background(), "do $$ begin raise exception 'retry me' using errcode = 'serialization_failure'; end $$")
+			return err
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, attempts)
+	require.Equal(t, 3, calls)
+
+	// Each attempt ran in a new transaction so only the last insert was committed.
+	var n int64
+	err = conn.QueryRow(context.Background(), "select count(*) from foo").Scan(&n)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, n)
+
+	ensureConnValid(t, conn)
+}
+
+func TestBeginTxFuncWithRetryGivesUp(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	deadlock := func(tx pgx.Tx) error {
+		_, err := tx.Exec(context.Background(), "do $$ begin raise exception 'retry me' using errcode = 'deadlock_detected'; end $$")
+		return err
+	}
+
+	attempts, err := pgx.BeginTxFuncWithRetry(context.Background(), conn, pgx.TxOptions{}, pgx.TxRetryPolicy{MaxAttempts: 2}, deadlock)
+	var pgErr *pgconn.PgError
+	require.True(t, errors.As(err, &pgErr))
+	require.Equal(t, "40P01", pgErr.Code)
+	require.Equal(t, 2, attempts)
+
+	// Other errors are not retried.
+	attempts, err = pgx.BeginTxFuncWithRetry(context.Background(), conn, pgx.TxOptions{}, pgx.TxRetryPolicy{}, func(tx pgx.Tx) error {
+		return errors.New("some error")
+	})
+	require.EqualError(t, err, "some error")
+	require.Equal(t, 1, attempts)
+
+	// Waiting to retry is interrupted by the context.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	attempts, err = pgx.BeginTxFuncWithRetry(ctx, conn, pgx.TxOptions{}, pgx.TxRetryPolicy{RetryDelay: time.Minute}, deadlock)
+	require.True(t, errors.Is(err, context.DeadlineExceeded))
+	require.Equal(t, 1, attempts)
+
+	ensureConnValid(t, conn)
+}
+
 func TestBeginReadOnly(t *testing.T) {
 	t.Parallel()
 
@@ -619,3 +687,181 @@ func TestTxSendBatchClosed(t *testing.T) {
 	_, err = br.Query()
 	require.Error(t, err)
 }
+
+func TestTxNamedSavepoints(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	_, err := conn.Exec(context.Background(), "create temporary table foo(id integer)")
+	require.NoError(t, err)
+
+	tx, err := conn.Begin(context.Background())
+	require.NoError(t, err)
+	defer tx.Rollback(context.Background())
+
+	_, err = tx.Exec(context.Background(), "insert into foo(id) values (1)")
+	require.NoError(t, err)
+
+	err = tx.Savepoint(context.Background(), "before_two")
+	require.NoError(t, err)
+
+	_, err = tx.Exec(context.Background(), "insert into foo(id) values (2)")
+	require.NoError(t, err)
+
+	err = tx.Savepoint(context.Background(), "before_three")
+	require.NoError(t, err)
+
+	_, err = tx.Exec(context.Background(), "insert into foo(id) values (3)")
+	require.NoError(t, err)
+
+	// Rolling back to before_two destroys before_three.
+	err = tx.RollbackToSavepoint(context.Background(), "before_two")
+	require.NoError(t, err)
+
+	err = tx.RollbackToSavepoint(context.Background(), "before_three")
+	require.True(t, errors.Is(err, pgx.ErrSavepointNotFound))
+
+	// The savepoint rolled back to can be rolled back to again.
+	_, err = tx.Exec(context.Background(), "insert into foo(id) values (4)")
+	require.NoError(t, err)
+	err = tx.RollbackToSavepoint(context.Background(), "before_two")
+	require.NoError(t, err)
+
+	err = tx.ReleaseSavepoint(context.Background(), "before_two")
+	require.NoError(t, err)
+
+	err = tx.ReleaseSavepoint(context.Background(), "before_two")
+	require.True(t, errors.Is(err, pgx.ErrSavepointNotFound))
+
+	err = tx.Commit(context.Background())
+	require.NoError(t, err)
+
+	var n int64
+	err = conn.QueryRow(context.Background(), "select count(*) from foo").Scan(&n)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, n)
+
+	ensureConnValid(t, conn)
+}
+
+func TestTxNamedSavepointWithNestedTransaction(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	tx, err := conn.Begin(context.Background())
+	require.NoError(t, err)
+	defer tx.Rollback(context.Background())
+
+	err = tx.Savepoint(context.Background(), "outer")
+	require.NoError(t, err)
+
+	nestedTx, err := tx.Begin(context.Background())
+	require.NoError(t, err)
+
+	err = nestedTx.Savepoint(context.Background(), "inner")
+	require.NoError(t, err)
+
+	// Rolling back to outer destroys the nested transaction's savepoint.
+	err = tx.RollbackToSavepoint(context.Background(), "outer")
+	require.NoError(t, err)
+
+	err = nestedTx.Commit(context.Background())
+	require.True(t, errors.Is(err, pgx.ErrSavepointNotFound))
+
+	err = tx.Commit(context.Background())
+	require.NoError(t, err)
+
+	ensureConnValid(t, conn)
+}
+
+func TestTxSavepointInvalidName(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	tx, err := conn.Begin(context.Background())
+	require.NoError(t, err)
+	defer tx.Rollback(context.Background())
+
+	for _, name := range []string{
+		"",
+		"1abc",
+		"a; rollback",
+		`a"b`,
+		"sp_1",
+		"abcdefghijklmnopqrstuvwxyzabcdefghijklmnopqrstuvwxyzabcdefghijkl",
+	} {
+		err = tx.Savepoint(context.Background(), name)
+		require.Error(t, err, name)
+
+		err = tx.RollbackToSavepoint(context.Background(), name)
+		require.Error(t, err, name)
+	}
+
+	// The transaction is not aborted by the rejected names.
+	_, err = tx.Exec(context.Background(), "select 1")
+	require.NoError(t, err)
+
+	err = tx.Savepoint(context.Background(), "_valid$name1")
+	require.NoError(t, err)
+
+	err = tx.Commit(context.Background())
+	require.NoError(t, err)
+
+	ensureConnValid(t, conn)
+}
+
+func TestTxSavepointNameIsCaseInsensitive(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	tx, err := conn.Begin(context.Background())
+	require.NoError(t, err)
+	defer tx.Rollback(context.Background())
+
+	err = tx.Savepoint(context.Background(), "Foo")
+	require.NoError(t, err)
+
+	// The savepoint has the name an unquoted identifier would have.
+	_, err = tx.Exec(context.Background(), "rollback to savepoint foo")
+	require.NoError(t, err)
+
+	err = tx.ReleaseSavepoint(context.Background(), "FOO")
+	require.NoError(t, err)
+
+	// A savepoint created with Exec is not tracked by the Tx.
+	_, err = tx.Exec(context.Background(), "savepoint bar")
+	require.NoError(t, err)
+	err = tx.ReleaseSavepoint(context.Background(), "bar")
+	require.True(t, errors.Is(err, pgx.ErrSavepointNotFound))
+
+	err = tx.Savepoint(context.Background(), "SP_1")
+	require.Error(t, err)
+
+	require.NoError(t, tx.Commit(context.Background()))
+
+	ensureConnValid(t, conn)
+}
+
+func TestTxSavepointClosed(t *testing.T) {
+	t.Parallel()
+
+	conn := mustConnectString(t, os.Getenv("PGX_TEST_DATABASE"))
+	defer closeConn(t, conn)
+
+	tx, err := conn.Begin(context.Background())
+	require.NoError(t, err)
+
+	err = tx.Rollback(context.Background())
+	require.NoError(t, err)
+
+	err = tx.Savepoint(context.Background(), "foo")
+	require.Equal(t, pgx.ErrTxClosed, err)
+}
@@ -146,7 +146,16 @@ func encodePreparedStatementArgument(ci *pgtype.ConnInfo, buf []byte, oid uint32
 		return pgio.AppendInt32(buf, -1), nil
 	}
 
+	// A nil pointer is always NULL. This must be checked before the encoder interfaces as calling a method on a nil
+	// pointer could panic.
+	refVal := reflect.ValueOf(arg)
+	if refVal.Kind() == reflect.Ptr && refVal.IsNil() {
+		return pgio.AppendInt32(buf, -1), nil
+	}
+
 	switch arg := arg.(type) {
+	case *rawCopyValue:
+		return arg.encodeCopy(ci, buf, oid)
 	case pgtype.BinaryEncoder:
 		sp := len(buf)
 		buf = pgio.AppendInt32(buf, -1)
@@ -160,16 +169,32 @@ func encodePreparedStatementArgument(ci *pgtype.ConnInfo, buf []byte, oid uint32
 		}
 		return buf, nil
 	case pgtype.TextEncoder:
-		sp := len(buf)
-		buf = pgio.AppendInt32(buf, -1)
-		argBuf, err := arg.EncodeText(ci, buf)
+		textBuf, err := arg.EncodeText(ci, nil)
 		if err != nil {
 			return nil, err
 		}
-		if argBuf != nil {
-			buf = argBuf
-			pgio.SetInt32(buf[sp:], int32(len(buf[sp:])-4))
+		if textBuf == nil {
+			return pgio.AppendInt32(buf, -1), nil
 		}
+
+		// The value must be in the binary format. If the type of oid is known, decode the text with it and encode it in
+		// the binary format. Otherwise, assume the text and binary formats are the same as they are for text and enum
+		// types.
+		if dt, ok := ci.DataTypeForOID(oid); ok {
+			value := pgtype.NewValue(dt.Value)
+			decoder, decoderOK := value.(pgtype.TextDecoder)
+			encoder, encoderOK := value.(pgtype.BinaryEncoder)
+			if decoderOK && encoderOK {
+				err = decoder.DecodeText(ci, textBuf)
+				if err != nil {
+					return nil, err
+				}
+				return encodePreparedStatementArgument(ci, buf, oid, encoder)
+			}
+		}
+
+		buf = pgio.AppendInt32(buf, int32(len(textBuf)))
+		buf = append(buf, textBuf...)
 		return buf, nil
 	case string:
 		buf = pgio.AppendInt32(buf, int32(len(arg)))
@@ -177,12 +202,7 @@ func encodePreparedStatementArgument(ci *pgtype.ConnInfo, buf []byte, oid uint32
 		return buf, nil
 	}
 
-	refVal := reflect.ValueOf(arg)
-
 	if refVal.Kind() == reflect.Ptr {
-		if refVal.IsNil() {
-			return pgio.AppendInt32(buf, -1), nil
-		}
 		arg = refVal.Elem().Interface()
 		return encodePreparedStatementArgument(ci, buf, oid, arg)
 	}
@@ -239,6 +259,15 @@ func chooseParameterFormatCode(ci *pgtype.ConnInfo, oid uint32, arg interface{})
 	return ci.ParamFormatCodeForOID(oid)
 }
 
+// canEncodeText returns false if arg can only be encoded in the binary format.
+func canEncodeText(arg interface{}) bool {
+	if _, ok := arg.(pgtype.BinaryEncoder); ok {
+		_, ok := arg.(pgtype.TextEncoder)
+		return ok
+	}
+	return true
+}
+
 func stripNamedType(val *reflect.Value) (interface{}, bool) {
 	switch val.Kind() {
 	case reflect.Int: